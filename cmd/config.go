@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stackrox/k8s-overlay-patch/pkg/transform"
+	"sigs.k8s.io/yaml"
+)
+
+// Helm cannot pass arguments to post-renderers, so the settings of the command can also be given through environment
+// variables or a config file in the working directory. Flags take precedence over environment variables, which take
+// precedence over the config file.
+const (
+	// envPatchFile is the environment variable that sets the patch file.
+	envPatchFile = "K8S_OVERLAY_PATCH_FILE"
+	// envNamespace is the environment variable that sets the namespace.
+	envNamespace = "K8S_OVERLAY_PATCH_NAMESPACE"
+	// envConfigFile is the environment variable that sets the config file, instead of searching the working directory.
+	envConfigFile = "K8S_OVERLAY_PATCH_CONFIG"
+	// envHelmNamespace is the namespace Helm runs in, used if no namespace is configured.
+	envHelmNamespace = "HELM_NAMESPACE"
+)
+
+// configFileNames are the names of the config files searched in the working directory, in order.
+var configFileNames = []string{".k8s-overlay-patch.yaml", "k8s-overlay-patch.yaml"}
+
+// config is the content of a config file.
+type config struct {
+	// PatchFile is the patch file, relative to the directory of the config file.
+	PatchFile string `json:"patchFile,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Strict    *bool  `json:"strict,omitempty"`
+	Verbose   *bool  `json:"verbose,omitempty"`
+	UseSchema *bool  `json:"useSchema,omitempty"`
+	Validate  *bool  `json:"validate,omitempty"`
+	// Settings are the settings of the transformers applied after the overlays.
+	transform.Settings
+}
+
+// loadConfig reads the config file named by K8S_OVERLAY_PATCH_CONFIG, or the first of configFileNames in dir. It
+// returns a nil config if there is none.
+func loadConfig(dir string) (*config, error) {
+	path := os.Getenv(envConfigFile)
+	if path == "" {
+		for _, name := range configFileNames {
+			candidate := filepath.Join(dir, name)
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+				break
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+		}
+	}
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &config{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, fmt.Errorf("config file %s: %v", path, err)
+	}
+	if c.PatchFile != "" && !filepath.IsAbs(c.PatchFile) {
+		c.PatchFile = filepath.Join(filepath.Dir(path), c.PatchFile)
+	}
+	return c, nil
+}
+
+// resolveSettings fills the settings whose flags are not set on cmd from the environment and the config file in dir.
+func resolveSettings(cmd *cobra.Command, dir string) error {
+	c, err := loadConfig(dir)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		c = &config{}
+	}
+	flags := cmd.Flags()
+	if !flags.Changed("patch-file") {
+		patchFilePath = firstNonEmpty(os.Getenv(envPatchFile), c.PatchFile)
+	}
+	if !flags.Changed("namespace") {
+		namespace = firstNonEmpty(os.Getenv(envNamespace), c.Namespace, os.Getenv(envHelmNamespace))
+	}
+	// The settings of the config file apply unless their flag is set.
+	fromFlags := settings
+	settings = c.Settings
+	flags.Visit(func(f *pflag.Flag) {
+		switch f.Name {
+		case "config-checksums":
+			settings.ConfigChecksums = fromFlags.ConfigChecksums
+		case "set-namespace":
+			settings.SetNamespace = fromFlags.SetNamespace
+		case "name-prefix":
+			settings.NamePrefix = fromFlags.NamePrefix
+		case "name-suffix":
+			settings.NameSuffix = fromFlags.NameSuffix
+		case "common-labels":
+			settings.CommonLabels = fromFlags.CommonLabels
+		case "common-annotations":
+			settings.CommonAnnotations = fromFlags.CommonAnnotations
+		case "extend-selectors":
+			settings.ExtendSelectors = fromFlags.ExtendSelectors
+		case "image-registry":
+			settings.ImageRegistries = fromFlags.ImageRegistries
+		}
+	})
+	for name, setting := range map[string]struct {
+		value *bool
+		conf  *bool
+	}{
+		"strict":     {&strict, c.Strict},
+		"verbose":    {&verbose, c.Verbose},
+		"use-schema": {&useSchema, c.UseSchema},
+		"validate":   {&validate, c.Validate},
+	} {
+		if !flags.Changed(name) && setting.conf != nil {
+			*setting.value = *setting.conf
+		}
+	}
+	if patchFilePath == "" {
+		return fmt.Errorf("no patch file: pass --patch-file, set %s or create %s in the working directory", envPatchFile, configFileNames[0])
+	}
+	return nil
+}
+
+// firstNonEmpty returns the first of values that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stackrox/k8s-overlay-patch/pkg/transform"
+	"github.com/stackrox/k8s-overlay-patch/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSettings(t *testing.T) {
+	tests := []struct {
+		desc          string
+		config        string
+		env           map[string]string
+		args          []string
+		wantPatchFile string
+		wantNamespace string
+		wantStrict    bool
+		wantSettings  transform.Settings
+		wantErr       string
+	}{
+		{
+			desc:    "nothing configured",
+			wantErr: "no patch file: pass --patch-file, set K8S_OVERLAY_PATCH_FILE or create .k8s-overlay-patch.yaml in the working directory",
+		},
+		{
+			desc:          "config file",
+			config:        "patchFile: patch.yaml\nnamespace: from-config\nstrict: true\n",
+			wantPatchFile: "patch.yaml",
+			wantNamespace: "from-config",
+			wantStrict:    true,
+		},
+		{
+			desc:          "environment over config file",
+			config:        "patchFile: patch.yaml\nnamespace: from-config\n",
+			env:           map[string]string{envPatchFile: "/env/patch.yaml", envNamespace: "from-env"},
+			wantPatchFile: "/env/patch.yaml",
+			wantNamespace: "from-env",
+		},
+		{
+			desc:          "flags over environment and config file",
+			config:        "patchFile: patch.yaml\nstrict: true\n",
+			env:           map[string]string{envPatchFile: "/env/patch.yaml", envNamespace: "from-env"},
+			args:          []string{"-p", "/flag/patch.yaml", "-n", "from-flag", "--strict=false"},
+			wantPatchFile: "/flag/patch.yaml",
+			wantNamespace: "from-flag",
+		},
+		{
+			desc:          "transformer settings",
+			config:        "patchFile: patch.yaml\nsetNamespace: from-config\nnamePrefix: config-\nconfigChecksums: true\n",
+			args:          []string{"--set-namespace", "from-flag", "--common-labels", "owner=team"},
+			wantPatchFile: "patch.yaml",
+			wantSettings: transform.Settings{
+				SetNamespace:    "from-flag",
+				NamePrefix:      "config-",
+				CommonLabels:    map[string]string{"owner": "team"},
+				ConfigChecksums: true,
+			},
+		},
+		{
+			desc:          "helm namespace",
+			env:           map[string]string{envPatchFile: "/env/patch.yaml", envHelmNamespace: "from-helm"},
+			wantPatchFile: "/env/patch.yaml",
+			wantNamespace: "from-helm",
+		},
+		{
+			desc:    "unknown config field",
+			config:  "patch: patch.yaml\n",
+			wantErr: `error unmarshaling JSON: while decoding JSON: json: unknown field "patch"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range []string{envPatchFile, envNamespace, envConfigFile, envHelmNamespace} {
+				t.Setenv(name, tt.env[name])
+			}
+			if tt.config != "" {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, configFileNames[0]), []byte(tt.config), 0o644))
+			}
+			cmd := &cobra.Command{}
+			addFlags(cmd.Flags())
+			require.NoError(t, cmd.Flags().Parse(tt.args))
+
+			err := resolveSettings(cmd, dir)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			wantPatchFile := tt.wantPatchFile
+			if !filepath.IsAbs(wantPatchFile) {
+				wantPatchFile = filepath.Join(dir, wantPatchFile)
+			}
+			assert.Equal(t, wantPatchFile, patchFilePath)
+			assert.Equal(t, tt.wantNamespace, namespace)
+			assert.Equal(t, tt.wantStrict, strict)
+			assert.Equal(t, tt.wantSettings, settings)
+		})
+	}
+}
+
+func TestLoadConfigFromEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "custom.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("patchFile: overlays/patch.yaml\nvalidate: true\ncommonLabels:\n  owner: team\nextendSelectors:\n- kind: Deployment\n  name: app\nimages:\n- registry: docker.io\n  newRegistry: mirror.example.com\n"), 0o644))
+	t.Setenv(envConfigFile, path)
+
+	c, err := loadConfig(t.TempDir())
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	assert.Equal(t, filepath.Join(dir, "overlays", "patch.yaml"), c.PatchFile)
+	require.NotNil(t, c.Validate)
+	assert.True(t, *c.Validate)
+	assert.Equal(t, map[string]string{"owner": "team"}, c.CommonLabels)
+	assert.Equal(t, []types.K8sObjectReference{{Kind: "Deployment", Name: "app"}}, c.ExtendSelectors)
+	assert.Equal(t, []transform.ImageRule{{Registry: "docker.io", NewRegistry: "mirror.example.com"}}, c.Images)
+}
+
+func TestExtendSelectorsFlag(t *testing.T) {
+	cmd := &cobra.Command{}
+	addFlags(cmd.Flags())
+	require.NoError(t, cmd.Flags().Parse([]string{"--extend-selectors", "Deployment/app,Service/app", "--extend-selectors", "PodDisruptionBudget/app"}))
+	assert.Equal(t, []types.K8sObjectReference{{Kind: "Deployment", Name: "app"}, {Kind: "Service", Name: "app"}, {Kind: "PodDisruptionBudget", Name: "app"}}, settings.ExtendSelectors)
+	assert.Equal(t, "[Deployment/app,Service/app,PodDisruptionBudget/app]", cmd.Flags().Lookup("extend-selectors").Value.String())
+
+	cmd = &cobra.Command{}
+	addFlags(cmd.Flags())
+	assert.ErrorContains(t, cmd.Flags().Parse([]string{"--extend-selectors", "app"}), `"app" is not an object reference of the form Kind/name`)
+}
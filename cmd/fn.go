@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+	"github.com/stackrox/k8s-overlay-patch/pkg/krm"
+)
+
+// fnCmd runs the command as a KRM function.
+var fnCmd = &cobra.Command{
+	Use:   "fn",
+	Short: "Runs as a KRM function for kustomize and kpt",
+	Long: `Runs as a KRM function: reads a ` + krm.APIVersion + ` ` + krm.Kind + ` from stdin, applies the overlays of its
+functionConfig to its items and writes the ResourceList with the patched items and results to stdout.
+
+The functionConfig holds the overlays and the settings of the patch file and config file at the top level:
+
+  apiVersion: stackrox.io/v1alpha1
+  kind: OverlayPatch
+  metadata:
+    name: patch
+  namespace: default
+  strict: true
+  overlays:
+  - apiVersion: v1
+    kind: ConfigMap
+    name: my-config-map
+    patches:
+    - path: data.foo
+      value: bar
+
+A ConfigMap can be used instead, with the overlays as a YAML string in data.overlays.
+
+The command also runs as a KRM function if the input of the root command is a ResourceList.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		in, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return err
+		}
+		return runFunction(cmd, in)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fnCmd)
+}
+
+// runFunction runs the KRM function on the ResourceList in and writes the resulting ResourceList, which holds the
+// results of a failed run as well, before returning the error of the run.
+func runFunction(cmd *cobra.Command, in []byte) error {
+	out, runErr := krm.Run(in)
+	if out != nil {
+		if _, err := cmd.OutOrStdout().Write(out); err != nil {
+			return err
+		}
+	}
+	return runErr
+}
@@ -4,32 +4,53 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"fmt"
+	"github.com/stackrox/k8s-overlay-patch/pkg/krm"
+	"github.com/stackrox/k8s-overlay-patch/pkg/openapi"
 	"github.com/stackrox/k8s-overlay-patch/pkg/patch"
+	"github.com/stackrox/k8s-overlay-patch/pkg/transform"
 	"github.com/stackrox/k8s-overlay-patch/pkg/types"
 	"io"
 	"os"
 	"sigs.k8s.io/yaml"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var patchFilePath string
 var manifestFilePath string
 var namespace string
 var outFile string
+var strict bool
+var verbose bool
+var useSchema bool
+var validate bool
+
+// settings are the settings of the transformers applied after the overlays.
+var settings transform.Settings
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "k8s-overlay-patch",
 	Short: "Applies overlays to rendered k8s manifests",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		patchFile, err := os.Open(patchFilePath)
-		if err != nil {
-			return err
-		}
-		defer patchFile.Close()
+	Long: `Applies overlays to rendered k8s manifests read from stdin or a manifest file.
+
+When run as a Helm post-renderer, which cannot be passed arguments, the patch file and namespace are taken from the
+` + envPatchFile + ` and ` + envNamespace + ` environment variables, or from a config file named
+` + configFileNames[0] + ` in the working directory:
+
+  patchFile: patch.yaml
+  namespace: default
+  strict: true
+
+Flags take precedence over environment variables, which take precedence over the config file.
 
+If the input is a KRM ResourceList, the command runs as a KRM function, see the fn command.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		var manifestFile *os.File
+		var err error
 		if manifestFilePath != "" {
 			manifestFile, err = os.Open(manifestFilePath)
 			if err != nil {
@@ -40,12 +61,22 @@ var rootCmd = &cobra.Command{
 			manifestFile = os.Stdin
 		}
 
-		patchBytes, err := io.ReadAll(patchFile)
+		manifestBytes, err := io.ReadAll(manifestFile)
 		if err != nil {
 			return err
 		}
+		if krm.IsResourceList(manifestBytes) {
+			return runFunction(cmd, manifestBytes)
+		}
 
-		manifestBytes, err := io.ReadAll(manifestFile)
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		if err := resolveSettings(cmd, wd); err != nil {
+			return err
+		}
+		patchBytes, err := os.ReadFile(patchFilePath)
 		if err != nil {
 			return err
 		}
@@ -55,7 +86,18 @@ var rootCmd = &cobra.Command{
 			return err
 		}
 
-		result, err := patch.YAMLManifestPatch(string(manifestBytes), namespace, overlayObj.Overlays)
+		opts := []patch.Option{patch.WithStrictPaths(strict), patch.WithVerboseErrors(verbose), patch.WithVars(overlayObj.Vars),
+			patch.WithAdditions(overlayObj.Add), patch.WithRemovalReporter(func(key string) {
+				cmd.PrintErrf("removed %s\n", key)
+			})}
+		if useSchema {
+			opts = append(opts, patch.WithSchemaProvider(openapi.Builtin()))
+		}
+		if validate {
+			opts = append(opts, patch.WithValidation(openapi.Builtin()))
+		}
+		opts = append(opts, patch.WithTransformers(settings.Transformers(namespace)...))
+		result, err := patch.YAMLManifestPatch(string(manifestBytes), namespace, overlayObj.Overlays, opts...)
 		if err != nil {
 			return err
 		}
@@ -86,8 +128,52 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.Flags().StringVarP(&patchFilePath, "patch-file", "p", "", "File containing the patch to apply")
-	rootCmd.Flags().StringVarP(&manifestFilePath, "manifest-file", "m", "", "File containing the rendered manifests to patch")
-	rootCmd.Flags().StringVarP(&namespace, "namespace", "n", "", "Namespace to use when patching the manifests")
-	rootCmd.Flags().StringVarP(&outFile, "out", "o", "", "File to write the patched manifests to")
+	addFlags(rootCmd.Flags())
+}
+
+// addFlags defines the flags of the command in flags.
+func addFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&patchFilePath, "patch-file", "p", "", "File containing the patch to apply")
+	flags.StringVarP(&manifestFilePath, "manifest-file", "m", "", "File containing the rendered manifests to patch")
+	flags.StringVarP(&namespace, "namespace", "n", "", "Namespace to use when patching the manifests")
+	flags.StringVarP(&outFile, "out", "o", "", "File to write the patched manifests to")
+	flags.BoolVar(&strict, "strict", false, "Fail patches with missing intermediate path nodes instead of creating them")
+	flags.BoolVar(&verbose, "verbose", false, "List all objects when an overlay does not match any object")
+	flags.BoolVar(&useSchema, "use-schema", false, "Convert patch values to the types declared by the Kubernetes API and manifest CRD schemas")
+	flags.BoolVar(&validate, "validate", false, "Validate patched objects against the Kubernetes API and manifest CRD schemas")
+	settings = transform.Settings{}
+	flags.BoolVar(&settings.ConfigChecksums, "config-checksums", false, "Annotate pod templates with checksums of the ConfigMaps and Secrets they use")
+	flags.StringVar(&settings.SetNamespace, "set-namespace", "", "Namespace to move all namespaced objects to, rewriting the references between them")
+	flags.StringVar(&settings.NamePrefix, "name-prefix", "", "Prefix to add to the names of all objects and the references between them")
+	flags.StringVar(&settings.NameSuffix, "name-suffix", "", "Suffix to add to the names of all objects and the references between them")
+	flags.StringToStringVar(&settings.CommonLabels, "common-labels", nil, "Labels to add to all objects and the pod templates of workloads")
+	flags.StringToStringVar(&settings.CommonAnnotations, "common-annotations", nil, "Annotations to add to all objects and the pod templates of workloads")
+	flags.StringToStringVar(&settings.ImageRegistries, "image-registry", nil, "Registries to replace in the images of all containers, as old=new")
+	flags.Var((*objectRefs)(&settings.ExtendSelectors), "extend-selectors", "Objects, as Kind/name, whose selectors to add the common labels to as well, for objects not in the cluster yet only")
+}
+
+// objectRefs is a pflag.Value of comma separated object references, as Kind/name.
+type objectRefs []types.K8sObjectReference
+
+func (r *objectRefs) String() string {
+	refs := make([]string, 0, len(*r))
+	for _, ref := range *r {
+		refs = append(refs, ref.Kind+"/"+ref.Name)
+	}
+	return "[" + strings.Join(refs, ",") + "]"
+}
+
+func (r *objectRefs) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		kind, name, ok := strings.Cut(v, "/")
+		if !ok || kind == "" || name == "" {
+			return fmt.Errorf("%q is not an object reference of the form Kind/name", v)
+		}
+		*r = append(*r, types.K8sObjectReference{Kind: kind, Name: name})
+	}
+	return nil
+}
+
+func (r *objectRefs) Type() string {
+	return "objects"
 }
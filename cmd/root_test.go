@@ -5,6 +5,8 @@ import (
 	"github.com/stackrox/k8s-overlay-patch/pkg/object"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -42,3 +44,43 @@ func TestRoot(t *testing.T) {
 	assert.Equal(t, "annotation", serviceU.UnstructuredObject().GetAnnotations()["my"])
 
 }
+
+func TestRootResourceList(t *testing.T) {
+	manifest := filepath.Join(t.TempDir(), "resource-list.yaml")
+	require.NoError(t, os.WriteFile(manifest, []byte(`apiVersion: config.kubernetes.io/v1
+kind: ResourceList
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: config
+  data:
+    key: value
+functionConfig:
+  kind: OverlayPatch
+  overlays:
+  - apiVersion: v1
+    kind: ConfigMap
+    name: config
+    patches:
+    - path: data.key
+      value: patched
+`), 0o600))
+
+	for _, args := range [][]string{{"-m", manifest}, {"fn"}} {
+		t.Run(args[0], func(t *testing.T) {
+			in, err := os.Open(manifest)
+			require.NoError(t, err)
+			defer in.Close()
+			rootCmd.SetIn(in)
+			rootCmd.SetArgs(args)
+			var wr = bytes.NewBufferString("")
+			rootCmd.SetOut(wr)
+			require.NoError(t, rootCmd.Execute())
+
+			assert.Contains(t, wr.String(), "kind: ResourceList")
+			assert.Contains(t, wr.String(), "key: patched")
+			assert.Contains(t, wr.String(), "severity: info")
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package helm
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"github.com/stackrox/k8s-overlay-patch/pkg/patch"
+	"github.com/stackrox/k8s-overlay-patch/pkg/types"
+)
+
+// sourceCommentPrefix starts the comment Helm adds to each rendered object to name its template.
+const sourceCommentPrefix = "# Source: "
+
+// PostRenderer applies overlays to the manifests rendered by Helm. It implements the PostRenderer interface of
+// helm.sh/helm/v3/pkg/postrender, so it can be set as the PostRenderer of an action.Install or action.Upgrade.
+type PostRenderer struct {
+	namespace string
+	overlays  []*types.K8sObjectOverlay
+	opts      []patch.Option
+}
+
+// NewPostRenderer returns a PostRenderer that applies overlays to the rendered manifests with patch.YAMLManifestPatch,
+// using namespace for objects that do not set one and the given patch options.
+func NewPostRenderer(namespace string, overlays []*types.K8sObjectOverlay, opts ...patch.Option) *PostRenderer {
+	return &PostRenderer{namespace: namespace, overlays: overlays, opts: opts}
+}
+
+// Run applies the overlays to renderedManifests and returns the patched manifests. Objects keep the # Source: comment
+// Helm added to them, so that Helm can still report the template an object came from.
+func (r *PostRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	in := renderedManifests.String()
+	removed := make(map[string]int)
+	opts := append(append([]patch.Option{}, r.opts...), patch.WithRemovalReporter(func(key string) {
+		removed[key]++
+	}))
+	patched, err := patch.YAMLManifestPatch(in, r.namespace, r.overlays, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewBufferString(addSourceComments(in, patched, removed)), nil
+}
+
+// addSourceComments adds the # Source: comments of the objects in the rendered manifest to the objects of the patched
+// manifest, and separates the objects as Helm does. removed counts the objects removed from the rendered manifest by
+// object.Hash. patch.YAMLManifestPatch writes the objects it keeps in manifest order, followed by the objects it adds,
+// so the objects are matched by position rather than by name, which transformers may change. Objects that overlays
+// added have no comment, and the comments of removed objects are dropped.
+func addSourceComments(rendered, patched string, removed map[string]int) string {
+	var sources []string
+	for _, doc := range objectDocuments(rendered) {
+		if key := doc.obj.Hash(); removed[key] > 0 {
+			removed[key]--
+			continue
+		}
+		sources = append(sources, sourceComment(doc.yaml))
+	}
+	var out strings.Builder
+	for i, doc := range objectDocuments(patched) {
+		out.WriteString("---\n")
+		if i < len(sources) && sources[i] != "" {
+			out.WriteString(sources[i] + "\n")
+		}
+		out.WriteString(strings.TrimSpace(doc.yaml) + "\n")
+	}
+	return out.String()
+}
+
+// sourceComment returns the # Source: comment of the document doc, or an empty string if it has none.
+func sourceComment(doc string) string {
+	for _, line := range strings.Split(doc, "\n") {
+		if strings.HasPrefix(line, sourceCommentPrefix) {
+			return line
+		}
+	}
+	return ""
+}
+
+// objectDocument is a document of a manifest that holds an object.
+type objectDocument struct {
+	yaml string
+	obj  *object.K8sObject
+}
+
+// objectDocuments returns the documents of manifest that hold an object, as patch.YAMLManifestPatch parses them.
+func objectDocuments(manifest string) []objectDocument {
+	var out []objectDocument
+	for _, doc := range object.SplitYAMLDocuments(manifest) {
+		objs, err := object.ParseK8sObjectsFromYAMLManifestFailOption(doc, false)
+		if err == nil && len(objs) == 1 {
+			out = append(out, objectDocument{yaml: doc, obj: objs[0]})
+		}
+	}
+	return out
+}
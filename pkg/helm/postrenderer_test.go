@@ -0,0 +1,180 @@
+package helm
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/patch"
+	"github.com/stackrox/k8s-overlay-patch/pkg/transform"
+	"github.com/stackrox/k8s-overlay-patch/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostRendererRun(t *testing.T) {
+	rendered := `---
+# Source: chart/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  key: value
+---
+# Source: chart/templates/empty.yaml
+---
+# Source: chart/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+`
+	overlays := []*types.K8sObjectOverlay{
+		{
+			ApiVersion: "apps/v1",
+			Kind:       "Deployment",
+			Name:       "app",
+			Patches:    []*types.K8sObjectOverlayPatch{{Path: "spec.replicas", Value: "3"}},
+		},
+		{
+			ApiVersion: "v1",
+			Kind:       "ConfigMap",
+			Name:       "config",
+			Patches:    []*types.K8sObjectOverlayPatch{{Path: "data.key", Value: "patched"}},
+		},
+	}
+
+	out, err := NewPostRenderer("default", overlays).Run(bytes.NewBufferString(rendered))
+	require.NoError(t, err)
+	assert.Equal(t, `---
+# Source: chart/templates/configmap.yaml
+apiVersion: v1
+data:
+  key: patched
+kind: ConfigMap
+metadata:
+  name: config
+---
+# Source: chart/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 3
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+`, out.String())
+}
+
+func TestPostRendererRunAddRemove(t *testing.T) {
+	rendered := `---
+# Source: chart/templates/psp.yaml
+apiVersion: policy/v1beta1
+kind: PodSecurityPolicy
+metadata:
+  name: psp
+---
+# Source: chart/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  key: value
+---
+# Source: chart/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+`
+	overlays := []*types.K8sObjectOverlay{{Kind: "PodSecurityPolicy", Name: "psp", Remove: true}}
+	adds := []*types.K8sObjectAddition{{CloneFrom: &types.K8sObjectReference{Kind: "ConfigMap", Name: "config"}, Name: "copy"}}
+
+	out, err := NewPostRenderer("default", overlays, patch.WithAdditions(adds)).Run(bytes.NewBufferString(rendered))
+	require.NoError(t, err)
+	assert.Equal(t, `---
+# Source: chart/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  key: value
+---
+# Source: chart/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+---
+apiVersion: v1
+data:
+  key: value
+kind: ConfigMap
+metadata:
+  name: copy
+`, out.String())
+}
+
+func TestPostRendererRunTransformers(t *testing.T) {
+	rendered := `---
+# Source: chart/templates/psp.yaml
+apiVersion: policy/v1beta1
+kind: PodSecurityPolicy
+metadata:
+  name: psp
+---
+# Source: chart/templates/serviceaccount.yaml
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: app
+---
+# Source: chart/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+`
+	overlays := []*types.K8sObjectOverlay{{Kind: "PodSecurityPolicy", Name: "psp", Remove: true}}
+	var reported []string
+	opts := []patch.Option{
+		patch.WithTransformers(transform.Rename("default", "team-", "")),
+		patch.WithRemovalReporter(func(key string) { reported = append(reported, key) }),
+	}
+
+	out, err := NewPostRenderer("default", overlays, opts...).Run(bytes.NewBufferString(rendered))
+	require.NoError(t, err)
+	assert.Equal(t, `---
+# Source: chart/templates/serviceaccount.yaml
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: team-app
+---
+# Source: chart/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: team-app
+`, out.String())
+	assert.Equal(t, []string{"PodSecurityPolicy::psp"}, reported)
+}
+
+func TestPostRendererRunError(t *testing.T) {
+	overlays := []*types.K8sObjectOverlay{{ApiVersion: "v1", Kind: "ConfigMap", Name: "missing"}}
+	_, err := NewPostRenderer("default", overlays).Run(bytes.NewBufferString("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n"))
+	assert.ErrorContains(t, err, "overlay for ConfigMap:missing does not match any object")
+}
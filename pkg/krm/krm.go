@@ -0,0 +1,273 @@
+// Package krm runs the patch engine as a KRM function, as specified by
+// https://github.com/kubernetes-sigs/kustomize/blob/master/cmd/config/docs/api-conventions/functions-spec.md, so that
+// kustomize and kpt can apply overlays to the objects they manage.
+package krm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"github.com/stackrox/k8s-overlay-patch/pkg/openapi"
+	"github.com/stackrox/k8s-overlay-patch/pkg/patch"
+	"github.com/stackrox/k8s-overlay-patch/pkg/transform"
+	"github.com/stackrox/k8s-overlay-patch/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// APIVersion is the API version of ResourceList.
+	APIVersion = "config.kubernetes.io/v1"
+	// Kind is the kind of ResourceList.
+	Kind = "ResourceList"
+)
+
+// Severities of results.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// ResourceList is the input and output of a KRM function.
+type ResourceList struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	// Items are the objects to patch, as JSON.
+	Items []json.RawMessage `json:"items"`
+	// FunctionConfig configures the function, see FunctionConfig.
+	FunctionConfig json.RawMessage `json:"functionConfig,omitempty"`
+	Results        []*Result       `json:"results,omitempty"`
+}
+
+// Result is a structured message about the run of the function.
+type Result struct {
+	Message     string       `json:"message"`
+	Severity    string       `json:"severity,omitempty"`
+	ResourceRef *ResourceRef `json:"resourceRef,omitempty"`
+	Field       *Field       `json:"field,omitempty"`
+}
+
+// ResourceRef identifies the object a Result is about.
+type ResourceRef struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+}
+
+// Field identifies the field of an object a Result is about.
+type Field struct {
+	Path string `json:"path"`
+}
+
+// FunctionConfig is the configuration of the function. It can be given as an object of any kind with these fields at
+// the top level, such as a patch file with an apiVersion, kind and metadata added, or as a ConfigMap whose data holds
+// the overlays, add and vars as YAML strings and the other settings as strings.
+type FunctionConfig struct {
+	// Namespace is the namespace used to match overlays to objects without a namespace.
+	Namespace string `json:"namespace,omitempty"`
+	// Vars are the variables available to templated patch values.
+	Vars map[string]any `json:"vars,omitempty"`
+	// Add are the objects to add to the items before the overlays are applied.
+	Add []*types.K8sObjectAddition `json:"add,omitempty"`
+	// Overlays are the overlays to apply to the items.
+	Overlays []*types.K8sObjectOverlay `json:"overlays,omitempty"`
+	// Strict fails patches with missing intermediate path nodes instead of creating them.
+	Strict bool `json:"strict,omitempty"`
+	// Verbose lists all objects when an overlay does not match any object.
+	Verbose bool `json:"verbose,omitempty"`
+	// UseSchema converts patch values to the types declared by the Kubernetes API and item CRD schemas.
+	UseSchema bool `json:"useSchema,omitempty"`
+	// Validate validates patched objects against the Kubernetes API and item CRD schemas.
+	Validate bool `json:"validate,omitempty"`
+	// Settings are the settings of the transformers applied to the items after the overlays.
+	transform.Settings
+}
+
+// IsResourceList reports whether in, the input of the command, is a ResourceList rather than a manifest.
+func IsResourceList(in []byte) bool {
+	var tm struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+	}
+	if err := yaml.Unmarshal(in, &tm); err != nil {
+		return false
+	}
+	return tm.APIVersion == APIVersion && tm.Kind == Kind
+}
+
+// Run reads a ResourceList from in, applies the overlays of its function config to its items and returns the
+// ResourceList to write back. If patching fails, the items are returned unchanged with a result for each error, and
+// the returned error is not nil, so that the function can exit with a non-zero status as the specification requires.
+func Run(in []byte) ([]byte, error) {
+	rl := &ResourceList{}
+	if err := yaml.Unmarshal(in, rl); err != nil {
+		return nil, fmt.Errorf("failed to parse ResourceList: %v", err)
+	}
+	if rl.APIVersion != APIVersion || rl.Kind != Kind {
+		return nil, fmt.Errorf("input must be a %s %s, not %s %s", APIVersion, Kind, rl.APIVersion, rl.Kind)
+	}
+	runErr := Process(rl)
+	out, err := yaml.Marshal(rl)
+	if err != nil {
+		return nil, err
+	}
+	return out, runErr
+}
+
+// Process applies the overlays of the function config of rl to its items, and appends results for the outcome.
+func Process(rl *ResourceList) error {
+	fc, err := parseFunctionConfig(rl.FunctionConfig)
+	if err != nil {
+		rl.Results = append(rl.Results, &Result{Message: err.Error(), Severity: SeverityError})
+		return err
+	}
+
+	manifest := make([]string, 0, len(rl.Items))
+	apiVersions := make(map[string]string, len(rl.Items))
+	for _, item := range rl.Items {
+		// JSON is YAML, so the items can be patched as a manifest without converting them.
+		manifest = append(manifest, string(item))
+		if obj, err := object.ParseJSONToK8sObject(item); err == nil {
+			apiVersions[obj.Hash()] = obj.UnstructuredObject().GetAPIVersion()
+		}
+	}
+
+	var removed []*Result
+	opts := []patch.Option{patch.WithStrictPaths(fc.Strict), patch.WithVerboseErrors(fc.Verbose), patch.WithVars(fc.Vars),
+		patch.WithAdditions(fc.Add), patch.WithRemovalReporter(func(key string) {
+			kind, namespace, name := object.FromHash(key)
+			removed = append(removed, &Result{
+				Message:     "removed by overlay",
+				Severity:    SeverityInfo,
+				ResourceRef: &ResourceRef{APIVersion: apiVersions[key], Kind: kind, Name: name, Namespace: namespace},
+			})
+		})}
+	if fc.UseSchema {
+		opts = append(opts, patch.WithSchemaProvider(openapi.Builtin()))
+	}
+	if fc.Validate {
+		opts = append(opts, patch.WithValidation(openapi.Builtin()))
+	}
+	opts = append(opts, patch.WithTransformers(fc.Transformers(fc.Namespace)...))
+
+	patched, err := patch.YAMLManifestPatch(strings.Join(manifest, object.YAMLSeparator), fc.Namespace, fc.Overlays, opts...)
+	if err != nil {
+		rl.Results = append(rl.Results, errorResults(err)...)
+		return err
+	}
+	objs, err := object.ParseK8sObjectsFromYAMLManifest(patched)
+	if err != nil {
+		rl.Results = append(rl.Results, errorResults(err)...)
+		return err
+	}
+	items := make([]json.RawMessage, 0, len(objs))
+	for _, obj := range objs {
+		j, err := obj.JSON()
+		if err != nil {
+			rl.Results = append(rl.Results, errorResults(err)...)
+			return err
+		}
+		items = append(items, j)
+	}
+	rl.Items = items
+	rl.Results = append(rl.Results, removed...)
+	rl.Results = append(rl.Results, &Result{
+		Message:  fmt.Sprintf("applied %d overlays to %d objects", len(fc.Overlays), len(items)),
+		Severity: SeverityInfo,
+	})
+	return nil
+}
+
+// parseFunctionConfig returns the FunctionConfig in fc, which may be a ConfigMap.
+func parseFunctionConfig(fc json.RawMessage) (*FunctionConfig, error) {
+	out := &FunctionConfig{}
+	if len(fc) == 0 || string(fc) == "null" {
+		return out, nil
+	}
+	var cm struct {
+		Kind string            `json:"kind"`
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(fc, &cm); err == nil && cm.Kind == "ConfigMap" {
+		return configMapFunctionConfig(cm.Data)
+	}
+	if err := json.Unmarshal(fc, out); err != nil {
+		return nil, fmt.Errorf("invalid functionConfig: %v", err)
+	}
+	return out, nil
+}
+
+// configMapFunctionConfig returns the FunctionConfig in the data of a ConfigMap.
+func configMapFunctionConfig(data map[string]string) (*FunctionConfig, error) {
+	out := &FunctionConfig{}
+	for key, setting := range map[string]any{
+		"namespace":         &out.Namespace,
+		"vars":              &out.Vars,
+		"add":               &out.Add,
+		"overlays":          &out.Overlays,
+		"strict":            &out.Strict,
+		"verbose":           &out.Verbose,
+		"useSchema":         &out.UseSchema,
+		"validate":          &out.Validate,
+		"setNamespace":      &out.SetNamespace,
+		"namePrefix":        &out.NamePrefix,
+		"nameSuffix":        &out.NameSuffix,
+		"commonLabels":      &out.CommonLabels,
+		"commonAnnotations": &out.CommonAnnotations,
+		"extendSelectors":   &out.ExtendSelectors,
+		"images":            &out.Images,
+		"imageRegistries":   &out.ImageRegistries,
+		"configChecksums":   &out.ConfigChecksums,
+	} {
+		v, ok := data[key]
+		if !ok {
+			continue
+		}
+		switch setting := setting.(type) {
+		case *string:
+			*setting = v
+		case *bool:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid functionConfig %s: %q is not a boolean", key, v)
+			}
+			*setting = b
+		default:
+			if err := yaml.Unmarshal([]byte(v), setting); err != nil {
+				return nil, fmt.Errorf("invalid functionConfig %s: %v", key, err)
+			}
+		}
+	}
+	return out, nil
+}
+
+// errorResults returns a result for each error in err, an error returned by patch.YAMLManifestPatch. Validation
+// errors get a result for each invalid field, referring to the invalid object.
+func errorResults(err error) []*Result {
+	switch e := err.(type) {
+	case *patch.ObjectValidationError:
+		kind, namespace, name := object.FromHash(e.Object)
+		ref := &ResourceRef{APIVersion: e.APIVersion, Kind: kind, Name: name, Namespace: namespace}
+		out := make([]*Result, 0, len(e.Errors))
+		for _, fe := range e.Errors {
+			out = append(out, &Result{
+				Message:     fe.ErrorBody(),
+				Severity:    SeverityError,
+				ResourceRef: ref,
+				Field:       &Field{Path: fe.Field},
+			})
+		}
+		return out
+	case interface{ Unwrap() []error }:
+		var out []*Result
+		for _, ee := range e.Unwrap() {
+			out = append(out, errorResults(ee)...)
+		}
+		return out
+	}
+	return []*Result{{Message: err.Error(), Severity: SeverityError}}
+}
@@ -0,0 +1,248 @@
+package krm
+
+import (
+	"testing"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/transform"
+	"github.com/stackrox/k8s-overlay-patch/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+const items = `
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: config
+    namespace: default
+    annotations:
+      config.kubernetes.io/index: "0"
+  data:
+    key: value
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: app
+    namespace: default
+  spec:
+    replicas: 1
+`
+
+func TestRun(t *testing.T) {
+	cases := []struct {
+		desc           string
+		functionConfig string
+		wantData       string
+		wantReplicas   float64
+	}{
+		{
+			desc: "overlay object",
+			functionConfig: `
+functionConfig:
+  apiVersion: stackrox.io/v1alpha1
+  kind: OverlayPatch
+  metadata:
+    name: patch
+  namespace: default
+  overlays:
+  - apiVersion: v1
+    kind: ConfigMap
+    name: config
+    patches:
+    - path: data.key
+      value: patched
+  - apiVersion: apps/v1
+    kind: Deployment
+    name: app
+    patches:
+    - path: spec.replicas
+      value: "3"
+`,
+			wantData:     "patched",
+			wantReplicas: 3,
+		},
+		{
+			desc: "ConfigMap",
+			functionConfig: `
+functionConfig:
+  apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: patch
+  data:
+    namespace: default
+    strict: "true"
+    overlays: |
+      - apiVersion: v1
+        kind: ConfigMap
+        name: config
+        patches:
+        - path: data.key
+          value: patched
+`,
+			wantData:     "patched",
+			wantReplicas: 1,
+		},
+		{
+			desc:         "no function config",
+			wantData:     "value",
+			wantReplicas: 1,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.desc, func(t *testing.T) {
+			in := "apiVersion: config.kubernetes.io/v1\nkind: ResourceList\n" + items + tt.functionConfig
+			require.True(t, IsResourceList([]byte(in)))
+			out, err := Run([]byte(in))
+			require.NoError(t, err)
+
+			var rl struct {
+				Items []struct {
+					Metadata struct {
+						Annotations map[string]string `json:"annotations"`
+					} `json:"metadata"`
+					Data map[string]string `json:"data"`
+					Spec map[string]any    `json:"spec"`
+				} `json:"items"`
+				Results []*Result `json:"results"`
+			}
+			require.NoError(t, yaml.Unmarshal(out, &rl))
+			require.Len(t, rl.Items, 2)
+			assert.Equal(t, "0", rl.Items[0].Metadata.Annotations["config.kubernetes.io/index"])
+			assert.Equal(t, tt.wantData, rl.Items[0].Data["key"])
+			assert.Equal(t, tt.wantReplicas, rl.Items[1].Spec["replicas"])
+			require.Len(t, rl.Results, 1)
+			assert.Equal(t, SeverityInfo, rl.Results[0].Severity)
+		})
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	in := `apiVersion: config.kubernetes.io/v1
+kind: ResourceList
+` + items + `
+functionConfig:
+  kind: OverlayPatch
+  namespace: default
+  validate: true
+  overlays:
+  - apiVersion: v1
+    kind: ConfigMap
+    name: config
+    patches:
+    - path: data.key
+      value: "3"
+  - apiVersion: v1
+    kind: Secret
+    name: missing
+`
+	out, err := Run([]byte(in))
+	require.Error(t, err)
+
+	var rl ResourceList
+	require.NoError(t, yaml.Unmarshal(out, &rl))
+	require.Len(t, rl.Items, 2)
+	assert.Contains(t, string(rl.Items[0]), `"key":"value"`)
+	assert.Equal(t, []*Result{
+		{
+			Message:  "overlay for Secret:missing does not match any object in output manifest. No similar objects found among 2 objects.",
+			Severity: SeverityError,
+		},
+		{
+			Message:     `Invalid value: 3: must be a string`,
+			Severity:    SeverityError,
+			ResourceRef: &ResourceRef{APIVersion: "v1", Kind: "ConfigMap", Name: "config", Namespace: "default"},
+			Field:       &Field{Path: "data[key]"},
+		},
+	}, rl.Results)
+}
+
+func TestRunRemove(t *testing.T) {
+	in := `apiVersion: config.kubernetes.io/v1
+kind: ResourceList
+` + items + `
+functionConfig:
+  kind: OverlayPatch
+  namespace: default
+  overlays:
+  - kind: ConfigMap
+    name: config
+    remove: true
+`
+	out, err := Run([]byte(in))
+	require.NoError(t, err)
+
+	var rl ResourceList
+	require.NoError(t, yaml.Unmarshal(out, &rl))
+	require.Len(t, rl.Items, 1)
+	assert.Contains(t, string(rl.Items[0]), `"kind":"Deployment"`)
+	assert.Equal(t, []*Result{
+		{
+			Message:     "removed by overlay",
+			Severity:    SeverityInfo,
+			ResourceRef: &ResourceRef{APIVersion: "v1", Kind: "ConfigMap", Name: "config", Namespace: "default"},
+		},
+		{
+			Message:  "applied 1 overlays to 1 objects",
+			Severity: SeverityInfo,
+		},
+	}, rl.Results)
+}
+
+func TestIsResourceList(t *testing.T) {
+	assert.True(t, IsResourceList([]byte("apiVersion: config.kubernetes.io/v1\nkind: ResourceList\nitems: []\n")))
+	assert.False(t, IsResourceList([]byte("apiVersion: v1\nkind: List\nitems: []\n")))
+	assert.False(t, IsResourceList([]byte("apiVersion: v1\nkind: ConfigMap\n---\napiVersion: v1\nkind: Secret\n")))
+	assert.False(t, IsResourceList([]byte("not: [yaml")))
+}
+
+func TestRunInvalidInput(t *testing.T) {
+	cases := []struct {
+		desc    string
+		in      string
+		wantErr string
+	}{
+		{
+			desc:    "not a ResourceList",
+			in:      "apiVersion: v1\nkind: ConfigMap\n",
+			wantErr: "input must be a config.kubernetes.io/v1 ResourceList, not v1 ConfigMap",
+		},
+		{
+			desc:    "invalid ConfigMap setting",
+			in:      "apiVersion: config.kubernetes.io/v1\nkind: ResourceList\nitems: []\nfunctionConfig:\n  kind: ConfigMap\n  data:\n    strict: maybe\n",
+			wantErr: `invalid functionConfig strict: "maybe" is not a boolean`,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.desc, func(t *testing.T) {
+			_, err := Run([]byte(tt.in))
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestParseFunctionConfigConfigMap(t *testing.T) {
+	fc, err := parseFunctionConfig([]byte(`{"kind": "ConfigMap", "data": {
+		"namespace": "default",
+		"validate": "true",
+		"setNamespace": "prod",
+		"commonLabels": "owner: team",
+		"extendSelectors": "- kind: Deployment\n  name: app",
+		"imageRegistries": "docker.io: mirror.example.com",
+		"configChecksums": "true"
+	}}`))
+	require.NoError(t, err)
+	assert.Equal(t, &FunctionConfig{
+		Namespace: "default",
+		Validate:  true,
+		Settings: transform.Settings{
+			SetNamespace:    "prod",
+			CommonLabels:    map[string]string{"owner": "team"},
+			ExtendSelectors: []types.K8sObjectReference{{Kind: "Deployment", Name: "app"}},
+			ImageRegistries: map[string]string{"docker.io": "mirror.example.com"},
+			ConfigChecksums: true,
+		},
+	}, fc)
+}
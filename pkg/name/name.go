@@ -19,4 +19,45 @@ const (
 	ClusterRoleStr        = "ClusterRole"
 	ClusterRoleBindingStr = "ClusterRoleBinding"
 	PDBStr                = "PodDisruptionBudget"
+	ConfigMapStr          = "ConfigMap"
+	SecretStr             = "Secret"
+	PodStr                = "Pod"
+	DeploymentStr         = "Deployment"
+	StatefulSetStr        = "StatefulSet"
+	DaemonSetStr          = "DaemonSet"
+	ReplicaSetStr         = "ReplicaSet"
+	JobStr                = "Job"
+	CronJobStr            = "CronJob"
+	ServiceStr            = "Service"
+	ServiceAccountStr     = "ServiceAccount"
+	PVCStr                = "PersistentVolumeClaim"
+	RoleStr               = "Role"
+	RoleBindingStr        = "RoleBinding"
+	NamespaceStr          = "Namespace"
+	CRDStr                = "CustomResourceDefinition"
+	APIServiceStr         = "APIService"
+	IngressStr            = "Ingress"
+	HPAStr                = "HorizontalPodAutoscaler"
+	MutatingWebhookStr    = "MutatingWebhookConfiguration"
+	ValidatingWebhookStr  = "ValidatingWebhookConfiguration"
+
+	ValidatingAdmissionPolicyStr        = "ValidatingAdmissionPolicy"
+	ValidatingAdmissionPolicyBindingStr = "ValidatingAdmissionPolicyBinding"
+	NodeStr                             = "Node"
+	PVStr                               = "PersistentVolume"
+	StorageClassStr                     = "StorageClass"
+	VolumeAttributesClassStr            = "VolumeAttributesClass"
+	CSIDriverStr                        = "CSIDriver"
+	CSINodeStr                          = "CSINode"
+	VolumeAttachmentStr                 = "VolumeAttachment"
+	PriorityClassStr                    = "PriorityClass"
+	RuntimeClassStr                     = "RuntimeClass"
+	IngressClassStr                     = "IngressClass"
+	PSPStr                              = "PodSecurityPolicy"
+	CSRStr                              = "CertificateSigningRequest"
+	ClusterTrustBundleStr               = "ClusterTrustBundle"
+	FlowSchemaStr                       = "FlowSchema"
+	PriorityLevelConfigurationStr       = "PriorityLevelConfiguration"
+	ServiceCIDRStr                      = "ServiceCIDR"
+	IPAddressStr                        = "IPAddress"
 )
@@ -25,7 +25,6 @@ import (
 	"sort"
 	"strings"
 
-	"github.com/stackrox/k8s-overlay-patch/pkg/helm"
 	names "github.com/stackrox/k8s-overlay-patch/pkg/name"
 	"github.com/stackrox/k8s-overlay-patch/pkg/tpath"
 	"github.com/stackrox/k8s-overlay-patch/pkg/util"
@@ -147,8 +146,11 @@ func (o *K8sObject) Unstructured() map[string]any {
 // Container returns a container subtree for Deployment objects if one is found, or nil otherwise.
 func (o *K8sObject) Container(name string) map[string]any {
 	u := o.Unstructured()
-	path := fmt.Sprintf("spec.template.spec.containers.[name:%s]", name)
-	node, f, err := tpath.GetPathContext(u, util.PathFromString(path), false)
+	path, err := util.ParsePath(fmt.Sprintf("spec.template.spec.containers.[name:%s]", name))
+	if err != nil {
+		return nil
+	}
+	node, f, err := tpath.GetPathContext(u, path, false)
 	if err == nil && f {
 		// Must be the type from the schema.
 		return node.Node.(map[string]any)
@@ -222,13 +224,33 @@ func (o *K8sObject) YAMLDebugString() string {
 // K8sObjects holds a collection of k8s objects, so that we can filter / sequence them
 type K8sObjects []*K8sObject
 
+// SplitYAMLDocuments splits manifest into the documents separated by lines starting with ---. The documents keep their
+// comments, and may be empty.
+func SplitYAMLDocuments(manifest string) []string {
+	var b strings.Builder
+	var yamls []string
+	scanner := bufio.NewScanner(strings.NewReader(manifest))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "---") {
+			// yaml separator
+			yamls = append(yamls, b.String())
+			b.Reset()
+		} else {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+	return append(yamls, b.String())
+}
+
 // String implements the Stringer interface.
 func (os K8sObjects) String() string {
 	var out []string
 	for _, oo := range os {
 		out = append(out, oo.YAMLDebugString())
 	}
-	return strings.Join(out, helm.YAMLSeparator)
+	return strings.Join(out, YAMLSeparator)
 }
 
 // Keys returns a slice with the keys of os.
@@ -257,26 +279,7 @@ func ParseK8sObjectsFromYAMLManifest(manifest string) (K8sObjects, error) {
 // ParseK8sObjectsFromYAMLManifestFailOption returns a K8sObjects representation of manifest. Continues parsing when a bad object
 // is found if failOnError is set to false.
 func ParseK8sObjectsFromYAMLManifestFailOption(manifest string, failOnError bool) (K8sObjects, error) {
-	var b bytes.Buffer
-
-	var yamls []string
-	scanner := bufio.NewScanner(strings.NewReader(manifest))
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "---") {
-			// yaml separator
-			yamls = append(yamls, b.String())
-			b.Reset()
-		} else {
-			if _, err := b.WriteString(line); err != nil {
-				return nil, err
-			}
-			if _, err := b.WriteString("\n"); err != nil {
-				return nil, err
-			}
-		}
-	}
-	yamls = append(yamls, b.String())
+	yamls := SplitYAMLDocuments(manifest)
 
 	var objects K8sObjects
 
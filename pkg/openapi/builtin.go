@@ -0,0 +1,209 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// builtinSchemeBuilder registers the built-in kinds Builtin knows.
+var builtinSchemeBuilder = runtime.NewSchemeBuilder(
+	admissionregistrationv1.AddToScheme,
+	apiextensionsv1.AddToScheme,
+	appsv1.AddToScheme,
+	autoscalingv1.AddToScheme,
+	autoscalingv2.AddToScheme,
+	batchv1.AddToScheme,
+	coordinationv1.AddToScheme,
+	corev1.AddToScheme,
+	discoveryv1.AddToScheme,
+	networkingv1.AddToScheme,
+	policyv1.AddToScheme,
+	rbacv1.AddToScheme,
+	schedulingv1.AddToScheme,
+	storagev1.AddToScheme,
+)
+
+var (
+	builtinOnce     sync.Once
+	builtinProvider *reflectProvider
+)
+
+// Builtin returns a Provider for the kinds of the core Kubernetes API groups, such as Pods, Deployments, Services,
+// RBAC and admission webhooks, and for CRDs themselves.
+// The schemas are derived from the Go types of the Kubernetes API, so they declare the properties and types of all
+// fields, but no enums, patterns or formats. Schemas of recursive types refer to themselves.
+func Builtin() Provider {
+	builtinOnce.Do(func() {
+		scheme := runtime.NewScheme()
+		if err := builtinSchemeBuilder.AddToScheme(scheme); err != nil {
+			panic(err)
+		}
+		builtinProvider = &reflectProvider{
+			types:   scheme.AllKnownTypes(),
+			schemas: make(map[reflect.Type]*apiextensionsv1.JSONSchemaProps),
+		}
+	})
+	return builtinProvider
+}
+
+// objectMetaSchema returns the schema of ObjectMeta.
+func objectMetaSchema() *apiextensionsv1.JSONSchemaProps {
+	p := Builtin().(*reflectProvider)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.typeSchema(reflect.TypeOf(metav1.ObjectMeta{}))
+}
+
+// reflectProvider is a Provider that derives schemas from Go types.
+type reflectProvider struct {
+	types map[schema.GroupVersionKind]reflect.Type
+
+	mu      sync.Mutex
+	schemas map[reflect.Type]*apiextensionsv1.JSONSchemaProps
+}
+
+// Schema implements Provider.
+func (p *reflectProvider) Schema(gvk schema.GroupVersionKind) *apiextensionsv1.JSONSchemaProps {
+	t, ok := p.types[gvk]
+	if !ok || strings.HasSuffix(gvk.Kind, "List") || gvk.Version == runtime.APIVersionInternal {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.typeSchema(t)
+}
+
+var (
+	rawMessageType   = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerTyp = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// typeSchema returns the schema of t, building and caching it if needed. p.mu must be held.
+func (p *reflectProvider) typeSchema(t reflect.Type) *apiextensionsv1.JSONSchemaProps {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if s, ok := p.schemas[t]; ok {
+		return s
+	}
+	s := &apiextensionsv1.JSONSchemaProps{}
+	// Cache the schema before filling it in, so that recursive types refer to it instead of recursing forever. Types,
+	// property maps, items and additional properties are set before recursing, so that the copies of incomplete
+	// schemas stored in properties share them with the completed schema.
+	p.schemas[t] = s
+
+	switch t {
+	case reflect.TypeOf(resource.Quantity{}), reflect.TypeOf(intstr.IntOrString{}):
+		s.XIntOrString = true
+		return s
+	case reflect.TypeOf(metav1.Time{}), reflect.TypeOf(metav1.MicroTime{}):
+		s.Type, s.Format = "string", "date-time"
+		return s
+	case reflect.TypeOf(metav1.Duration{}):
+		s.Type = "string"
+		return s
+	case rawMessageType:
+		s.XPreserveUnknownFields = ptrTo(true)
+		return s
+	}
+	if t.Kind() == reflect.Struct && reflect.PointerTo(t).Implements(jsonMarshalerTyp) {
+		// Types with custom JSON encoding, such as RawExtension and FieldsV1, can hold anything.
+		s.XPreserveUnknownFields = ptrTo(true)
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		s.Type = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s.Type = "integer"
+	case reflect.Float32, reflect.Float64:
+		s.Type = "number"
+	case reflect.String:
+		s.Type = "string"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			s.Type, s.Format = "string", "byte"
+			break
+		}
+		s.Type = "array"
+		s.Items = &apiextensionsv1.JSONSchemaPropsOrArray{}
+		s.Items.Schema = p.typeSchema(t.Elem())
+	case reflect.Map:
+		s.Type = "object"
+		s.AdditionalProperties = &apiextensionsv1.JSONSchemaPropsOrBool{Allows: true}
+		s.AdditionalProperties.Schema = p.typeSchema(t.Elem())
+	case reflect.Struct:
+		s.Type = "object"
+		s.Properties = make(map[string]apiextensionsv1.JSONSchemaProps)
+		p.addFields(s, t)
+	default:
+		s.XPreserveUnknownFields = ptrTo(true)
+	}
+	return s
+}
+
+// addFields adds the properties of the JSON encoding of struct type t to s. Scalar and struct fields without omitempty
+// are required.
+func (p *reflectProvider) addFields(s *apiextensionsv1.JSONSchemaProps, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		if (f.Anonymous && name == "") || strings.Contains(opts, "inline") {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				p.addFields(s, ft)
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = *p.typeSchema(f.Type)
+		switch f.Type.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+			// Nil values are common for optional fields that lack omitempty, such as the rules of aggregated ClusterRoles.
+		default:
+			if !strings.Contains(opts, "omitempty") {
+				s.Required = append(s.Required, name)
+			}
+		}
+	}
+}
+
+func ptrTo[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,172 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/util"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// Lookup returns the schema of the node at path in an object with schema s, or nil if the schema of the node is not
+// known. Key path elements select properties or additional properties, index and selector path elements select the
+// items of arrays.
+func Lookup(s *apiextensionsv1.JSONSchemaProps, path util.Path) *apiextensionsv1.JSONSchemaProps {
+	for _, pe := range path {
+		if s == nil {
+			return nil
+		}
+		if _, ok := util.RemoveBrackets(pe); ok {
+			s = itemsSchema(s)
+			continue
+		}
+		s = propertySchema(s, util.PathKey(pe))
+	}
+	return s
+}
+
+// propertySchema returns the schema of the property key of an object with schema s, or nil if it is not known.
+func propertySchema(s *apiextensionsv1.JSONSchemaProps, key string) *apiextensionsv1.JSONSchemaProps {
+	if p, ok := s.Properties[key]; ok {
+		return &p
+	}
+	if s.AdditionalProperties != nil {
+		return s.AdditionalProperties.Schema
+	}
+	return nil
+}
+
+// itemsSchema returns the schema of the items of an array with schema s, or nil if it is not known.
+func itemsSchema(s *apiextensionsv1.JSONSchemaProps) *apiextensionsv1.JSONSchemaProps {
+	if s.Items == nil {
+		return nil
+	}
+	return s.Items.Schema
+}
+
+// Coerce converts v, a value decoded from YAML or JSON, to the types declared by schema s: strings that hold numbers
+// or booleans are parsed for integer, number and boolean fields, numbers and booleans are formatted for string fields,
+// and strings holding YAML are unmarshaled for object and array fields. Maps and lists are converted recursively.
+// A nil v or schema leaves v as it is, as do int-or-string fields and fields without a declared type.
+// A scalar v for an array field is converted to the item type, since writing a scalar to a list appends it.
+// It returns an error naming path if v cannot be converted.
+func Coerce(s *apiextensionsv1.JSONSchemaProps, path util.Path, v any) (any, error) {
+	if s == nil || v == nil || s.XIntOrString {
+		return v, nil
+	}
+	switch s.Type {
+	case "string":
+		switch vv := v.(type) {
+		case string:
+			return vv, nil
+		case bool:
+			return strconv.FormatBool(vv), nil
+		case float64:
+			return strconv.FormatFloat(vv, 'f', -1, 64), nil
+		case int, int64, uint64, util.Number:
+			return fmt.Sprint(vv), nil
+		}
+	case "integer":
+		switch vv := v.(type) {
+		case int, int64, uint64:
+			return vv, nil
+		case float64:
+			if vv == math.Trunc(vv) && math.Abs(vv) < 1<<63 {
+				return int64(vv), nil
+			}
+		case util.Number:
+			if f, err := vv.Float64(); err == nil && f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+				return int64(f), nil
+			}
+		case string:
+			if i, err := strconv.ParseInt(vv, 10, 64); err == nil {
+				return i, nil
+			}
+			if u, err := strconv.ParseUint(vv, 10, 64); err == nil {
+				return u, nil
+			}
+		}
+	case "number":
+		switch vv := v.(type) {
+		case int, int64, uint64, float64, util.Number:
+			return vv, nil
+		case string:
+			if i, err := strconv.ParseInt(vv, 10, 64); err == nil {
+				return i, nil
+			}
+			if f, err := strconv.ParseFloat(vv, 64); err == nil {
+				return f, nil
+			}
+		}
+	case "boolean":
+		switch vv := v.(type) {
+		case bool:
+			return vv, nil
+		case string:
+			if vv == "true" || vv == "false" {
+				return vv == "true", nil
+			}
+		}
+	case "object":
+		if str, ok := v.(string); ok {
+			m, err := util.UnmarshalValue(str)
+			if err != nil {
+				return nil, fmt.Errorf("path %s: cannot convert %q to object: %v", path, str, err)
+			}
+			if m == nil {
+				m = map[string]any{}
+			}
+			v = m
+		}
+		switch vv := v.(type) {
+		case map[string]any:
+			for k, e := range vv {
+				c, err := Coerce(propertySchema(s, k), append(path[:len(path):len(path)], k), e)
+				if err != nil {
+					return nil, err
+				}
+				vv[k] = c
+			}
+			return vv, nil
+		case map[any]any:
+			for k, e := range vv {
+				c, err := Coerce(propertySchema(s, fmt.Sprint(k)), append(path[:len(path):len(path)], fmt.Sprint(k)), e)
+				if err != nil {
+					return nil, err
+				}
+				vv[k] = c
+			}
+			return vv, nil
+		}
+	case "array":
+		lst, ok := v.([]any)
+		if !ok {
+			return Coerce(itemsSchema(s), path, v)
+		}
+		for i, e := range lst {
+			c, err := Coerce(itemsSchema(s), append(path[:len(path):len(path)], fmt.Sprintf("[%d]", i)), e)
+			if err != nil {
+				return nil, err
+			}
+			lst[i] = c
+		}
+		return lst, nil
+	default:
+		return v, nil
+	}
+	return nil, fmt.Errorf("path %s: cannot convert %s to %s", path, describe(v), s.Type)
+}
+
+// describe returns a short description of v for error messages.
+func describe(v any) string {
+	switch vv := v.(type) {
+	case string:
+		return strconv.Quote(vv)
+	case map[string]any, map[any]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	return fmt.Sprintf("%v", v)
+}
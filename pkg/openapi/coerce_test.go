@@ -0,0 +1,245 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/util"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var deploymentGVK = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		path     string
+		wantType string
+		wantNil  bool
+		intOrStr bool
+	}{
+		{path: "spec.replicas", wantType: "integer"},
+		{path: "spec.paused", wantType: "boolean"},
+		{path: "metadata.annotations.foo", wantType: "string"},
+		{path: "spec.template.spec.containers.[name:app].ports.[0].containerPort", wantType: "integer"},
+		{path: "spec.template.spec.containers.[name:app].args.[--v=1]", wantType: "string"},
+		{path: "spec.template.spec.containers.[name:app].resources.limits.cpu", intOrStr: true},
+		{path: "spec.strategy.rollingUpdate.maxSurge", intOrStr: true},
+		{path: "spec.template.spec.containers", wantType: "array"},
+		{path: "spec.unknown", wantNil: true},
+		{path: "spec.replicas.foo", wantNil: true},
+	}
+	root := Builtin().Schema(deploymentGVK)
+	if root == nil {
+		t.Fatal("no schema for Deployment")
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got := Lookup(root, mustPath(t, tt.path))
+			if tt.wantNil {
+				if got != nil {
+					t.Errorf("Lookup(%s): got:%s, want nil", tt.path, got.Type)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("Lookup(%s): got nil", tt.path)
+			}
+			if got.Type != tt.wantType || got.XIntOrString != tt.intOrStr {
+				t.Errorf("Lookup(%s): got:%q int-or-string:%v, want:%q int-or-string:%v", tt.path, got.Type, got.XIntOrString, tt.wantType, tt.intOrStr)
+			}
+		})
+	}
+}
+
+func TestCoerce(t *testing.T) {
+	tests := []struct {
+		desc    string
+		path    string
+		in      any
+		want    any
+		wantErr string
+	}{
+		{
+			desc: "string to integer",
+			path: "spec.replicas",
+			in:   "3",
+			want: int64(3),
+		},
+		{
+			desc: "float to integer",
+			path: "spec.replicas",
+			in:   3.0,
+			want: int64(3),
+		},
+		{
+			desc:    "fraction to integer",
+			path:    "spec.replicas",
+			in:      3.5,
+			wantErr: "path spec.replicas: cannot convert 3.5 to integer",
+		},
+		{
+			desc:    "word to integer",
+			path:    "spec.replicas",
+			in:      "three",
+			wantErr: `path spec.replicas: cannot convert "three" to integer`,
+		},
+		{
+			desc: "decimal to integer",
+			path: "spec.replicas",
+			in:   util.Number("3.0"),
+			want: int64(3),
+		},
+		{
+			desc: "decimal to string",
+			path: "metadata.annotations.version",
+			in:   util.Number("1.10"),
+			want: "1.10",
+		},
+		{
+			desc: "large integer to string",
+			path: "metadata.annotations.size",
+			in:   int64(9007199254740993),
+			want: "9007199254740993",
+		},
+		{
+			desc: "number to string",
+			path: "metadata.annotations.port",
+			in:   8080.0,
+			want: "8080",
+		},
+		{
+			desc: "large number to string",
+			path: "metadata.annotations.size",
+			in:   1e7,
+			want: "10000000",
+		},
+		{
+			desc: "bool to string",
+			path: "metadata.labels.enabled",
+			in:   true,
+			want: "true",
+		},
+		{
+			desc: "YAML looking string stays a string",
+			path: "metadata.annotations.note",
+			in:   "note: keep",
+			want: "note: keep",
+		},
+		{
+			desc:    "object to string",
+			path:    "metadata.annotations.note",
+			in:      map[string]any{"a": "b"},
+			wantErr: "path metadata.annotations.note: cannot convert object to string",
+		},
+		{
+			desc: "string to boolean",
+			path: "spec.paused",
+			in:   "true",
+			want: true,
+		},
+		{
+			desc:    "yes to boolean",
+			path:    "spec.paused",
+			in:      "yes",
+			wantErr: `path spec.paused: cannot convert "yes" to boolean`,
+		},
+		{
+			desc: "int or string is kept",
+			path: "spec.strategy.rollingUpdate.maxSurge",
+			in:   "25%",
+			want: "25%",
+		},
+		{
+			desc: "nested object",
+			path: "spec.template.spec.securityContext",
+			in:   map[string]any{"runAsUser": "1000", "runAsNonRoot": "true"},
+			want: map[string]any{"runAsUser": int64(1000), "runAsNonRoot": true},
+		},
+		{
+			desc: "object from YAML string",
+			path: "spec.template.spec.nodeSelector",
+			in:   "disk: ssd\nzone: 1",
+			want: map[string]any{"disk": "ssd", "zone": "1"},
+		},
+		{
+			desc: "large integer in object from YAML string",
+			path: "spec.template.spec.securityContext",
+			in:   "runAsUser: 9007199254740993",
+			want: map[string]any{"runAsUser": int64(9007199254740993)},
+		},
+		{
+			desc: "large integer in object from JSON string",
+			path: "spec.template.spec.securityContext",
+			in:   `{"runAsGroup": 9007199254740993, "runAsNonRoot": "true"}`,
+			want: map[string]any{"runAsGroup": int64(9007199254740993), "runAsNonRoot": true},
+		},
+		{
+			desc:    "scalar YAML string to object",
+			path:    "spec.template.spec.nodeSelector",
+			in:      "ssd",
+			wantErr: `path spec.template.spec.nodeSelector: cannot convert "ssd" to object`,
+		},
+		{
+			desc: "array items",
+			path: "spec.template.spec.containers.[name:app].ports",
+			in:   []any{map[string]any{"containerPort": "8080", "name": "http"}},
+			want: []any{map[string]any{"containerPort": int64(8080), "name": "http"}},
+		},
+		{
+			desc: "scalar appended to array",
+			path: "spec.template.spec.containers.[name:app].args",
+			in:   2.0,
+			want: "2",
+		},
+		{
+			desc:    "error path of nested value",
+			path:    "spec.template.spec.containers.[name:app].ports",
+			in:      []any{map[string]any{"containerPort": "http"}},
+			wantErr: `path spec.template.spec.containers.[name:app].ports.[0].containerPort: cannot convert "http" to integer`,
+		},
+		{
+			desc: "nil",
+			path: "spec.replicas",
+			in:   nil,
+			want: nil,
+		},
+		{
+			desc: "unknown field",
+			path: "spec.unknown",
+			in:   "3",
+			want: "3",
+		},
+	}
+	root := Builtin().Schema(deploymentGVK)
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			path := mustPath(t, tt.path)
+			got, err := Coerce(Lookup(root, path), path, tt.in)
+			if gotErr := errToString(err); gotErr != tt.wantErr {
+				t.Fatalf("%s: gotErr:%s, wantErr:%s", tt.desc, gotErr, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: got:%v(%T), want:%v(%T)", tt.desc, got, got, tt.want, tt.want)
+			}
+		})
+	}
+}
+
+// errToString returns the string representation of err and the empty string if
+// err is nil.
+func errToString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// mustPath returns the path s, failing t if it does not parse.
+func mustPath(t *testing.T, s string) util.Path {
+	t.Helper()
+	p, err := util.ParsePath(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
@@ -0,0 +1,81 @@
+// Package openapi provides OpenAPI v3 schemas of Kubernetes objects, and uses them to convert patch values to the
+// types declared for the fields they are written to.
+package openapi
+
+import (
+	"fmt"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Provider returns the OpenAPI v3 schemas of Kubernetes objects.
+type Provider interface {
+	// Schema returns the schema of objects of the given group, version and kind, or nil if it is not known.
+	Schema(gvk schema.GroupVersionKind) *apiextensionsv1.JSONSchemaProps
+}
+
+// Providers is a Provider that returns the schema of the first of its providers that knows the kind.
+type Providers []Provider
+
+// Schema implements Provider.
+func (ps Providers) Schema(gvk schema.GroupVersionKind) *apiextensionsv1.JSONSchemaProps {
+	for _, p := range ps {
+		if p == nil {
+			continue
+		}
+		if s := p.Schema(gvk); s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+// crdProvider is a Provider for the kinds defined by a set of CRDs.
+type crdProvider map[schema.GroupVersionKind]*apiextensionsv1.JSONSchemaProps
+
+// Schema implements Provider.
+func (p crdProvider) Schema(gvk schema.GroupVersionKind) *apiextensionsv1.JSONSchemaProps {
+	return p[gvk]
+}
+
+// FromCRDs returns a Provider for the kinds defined by the openAPIV3Schema of each version of the given CRDs.
+// CRD schemas usually leave metadata unspecified, the schema of ObjectMeta is used for it in that case.
+func FromCRDs(crds ...*apiextensionsv1.CustomResourceDefinition) Provider {
+	p := make(crdProvider)
+	for _, crd := range crds {
+		for _, v := range crd.Spec.Versions {
+			if v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+				continue
+			}
+			s := v.Schema.OpenAPIV3Schema.DeepCopy()
+			if meta, ok := s.Properties["metadata"]; !ok || len(meta.Properties) == 0 {
+				if s.Properties == nil {
+					s.Properties = make(map[string]apiextensionsv1.JSONSchemaProps)
+				}
+				s.Properties["metadata"] = *objectMetaSchema()
+			}
+			p[schema.GroupVersionKind{Group: crd.Spec.Group, Version: v.Name, Kind: crd.Spec.Names.Kind}] = s
+		}
+	}
+	return p
+}
+
+// FromManifestCRDs returns a Provider for the kinds defined by the apiextensions.k8s.io/v1 CRDs in objs.
+func FromManifestCRDs(objs object.K8sObjects) (Provider, error) {
+	var crds []*apiextensionsv1.CustomResourceDefinition
+	for _, obj := range objs {
+		gvk := obj.GroupVersionKind()
+		if gvk.Group != apiextensionsv1.GroupName || gvk.Version != "v1" || gvk.Kind != "CustomResourceDefinition" {
+			continue
+		}
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Unstructured(), crd); err != nil {
+			return nil, fmt.Errorf("CRD %s: %v", obj.Name, err)
+		}
+		crds = append(crds, crd)
+	}
+	return FromCRDs(crds...), nil
+}
@@ -0,0 +1,101 @@
+package openapi
+
+import (
+	"testing"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestFromManifestCRDs(t *testing.T) {
+	manifest := `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    plural: widgets
+  scope: Namespaced
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: false
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            x-kubernetes-preserve-unknown-fields: true
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              size:
+                type: integer
+`
+	objs, err := object.ParseK8sObjectsFromYAMLManifest(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := FromManifestCRDs(objs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		desc     string
+		gvk      schema.GroupVersionKind
+		path     string
+		wantType string
+	}{
+		{
+			desc:     "declared field",
+			gvk:      schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"},
+			path:     "spec.size",
+			wantType: "integer",
+		},
+		{
+			desc: "field of another version",
+			gvk:  schema.GroupVersionKind{Group: "example.com", Version: "v1alpha1", Kind: "Widget"},
+			path: "spec.size",
+		},
+		{
+			desc:     "metadata from ObjectMeta",
+			gvk:      schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"},
+			path:     "metadata.labels.app",
+			wantType: "string",
+		},
+		{
+			desc: "unknown kind",
+			gvk:  schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Gadget"},
+			path: "spec.size",
+		},
+		{
+			desc:     "built-in kind after CRDs",
+			gvk:      deploymentGVK,
+			path:     "spec.replicas",
+			wantType: "integer",
+		},
+	}
+	providers := Providers{p, nil, Builtin()}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var gotType string
+			if s := Lookup(providers.Schema(tt.gvk), mustPath(t, tt.path)); s != nil {
+				gotType = s.Type
+			}
+			if gotType != tt.wantType {
+				t.Errorf("%s: got:%q, want:%q", tt.desc, gotType, tt.wantType)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/util"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	"k8s.io/apimachinery/pkg/api/validation/path"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ValidateObject validates obj, a Kubernetes object decoded from YAML or JSON, against its schema s, which may be nil
+// if it is not known. It reports unknown fields, values that have the wrong type or are not one of the values of an
+// enum, and missing required fields. The metadata of obj is validated as the API server does on creation, including
+// the syntax of its name, namespace, labels and annotations.
+func ValidateObject(s *apiextensionsv1.JSONSchemaProps, obj map[string]any) field.ErrorList {
+	var errs field.ErrorList
+	if s != nil {
+		errs = append(errs, validateValue(s, obj, nil)...)
+	}
+	return append(errs, validateMetadata(obj)...)
+}
+
+// resourceFields are the fields of every Kubernetes object, which schemas of CRDs need not declare.
+var resourceFields = map[string]bool{"apiVersion": true, "kind": true, "metadata": true}
+
+// validateValue validates v against s. Null values are treated as unset.
+func validateValue(s *apiextensionsv1.JSONSchemaProps, v any, fldPath *field.Path) field.ErrorList {
+	if s == nil || v == nil {
+		return nil
+	}
+	if s.XIntOrString {
+		if _, ok := v.(string); !ok && !isInteger(v) {
+			return field.ErrorList{field.TypeInvalid(fldPath, v, "must be an integer or a string")}
+		}
+		return nil
+	}
+
+	var errs field.ErrorList
+	switch s.Type {
+	case "object":
+		m, ok := v.(map[string]any)
+		if !ok {
+			return field.ErrorList{field.TypeInvalid(fldPath, v, "must be an object")}
+		}
+		errs = append(errs, validateObject(s, m, fldPath)...)
+	case "array":
+		l, ok := v.([]any)
+		if !ok {
+			return field.ErrorList{field.TypeInvalid(fldPath, v, "must be an array")}
+		}
+		for i, e := range l {
+			errs = append(errs, validateValue(itemsSchema(s), e, fldPath.Index(i))...)
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return field.ErrorList{field.TypeInvalid(fldPath, v, "must be a string")}
+		}
+	case "integer":
+		if !isInteger(v) {
+			return field.ErrorList{field.TypeInvalid(fldPath, v, "must be an integer")}
+		}
+	case "number":
+		if !isInteger(v) && !isNumber(v) {
+			return field.ErrorList{field.TypeInvalid(fldPath, v, "must be a number")}
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return field.ErrorList{field.TypeInvalid(fldPath, v, "must be a boolean")}
+		}
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		errs = append(errs, field.NotSupported(fldPath, v, enumValues(s.Enum)))
+	}
+	return errs
+}
+
+// validateObject validates the fields of m against the object schema s.
+func validateObject(s *apiextensionsv1.JSONSchemaProps, m map[string]any, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for _, r := range s.Required {
+		if _, ok := m[r]; !ok {
+			errs = append(errs, field.Required(fldPath.Child(r), ""))
+		}
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if p, ok := s.Properties[k]; ok {
+			errs = append(errs, validateValue(&p, m[k], fldPath.Child(k))...)
+			continue
+		}
+		switch {
+		case s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil:
+			errs = append(errs, validateValue(s.AdditionalProperties.Schema, m[k], fldPath.Key(k))...)
+		case s.AdditionalProperties != nil && s.AdditionalProperties.Allows:
+		case s.XPreserveUnknownFields != nil && *s.XPreserveUnknownFields:
+		case (fldPath == nil || s.XEmbeddedResource) && resourceFields[k]:
+		default:
+			errs = append(errs, unknownFieldError(s, fldPath, k))
+		}
+	}
+	return errs
+}
+
+// unknownFieldError returns the error for the field key of the object at fldPath, which its schema s does not declare.
+// It suggests similarly named fields that s declares.
+func unknownFieldError(s *apiextensionsv1.JSONSchemaProps, fldPath *field.Path, key string) *field.Error {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	detail := "unknown field"
+	if similar := util.ClosestStrings(key, names); len(similar) > 0 {
+		detail += fmt.Sprintf(", did you mean %s?", util.QuoteList(similar, "or"))
+	}
+	return field.Forbidden(fldPath.Child(key), detail)
+}
+
+// isInteger reports whether v is a whole number.
+func isInteger(v any) bool {
+	switch vv := v.(type) {
+	case int, int64, uint64:
+		return true
+	case float64:
+		return vv == math.Trunc(vv) && !math.IsInf(vv, 0)
+	case util.Number:
+		f, err := vv.Float64()
+		return err == nil && f == math.Trunc(f)
+	}
+	return false
+}
+
+// isNumber reports whether v is a number.
+func isNumber(v any) bool {
+	switch v.(type) {
+	case int, int64, uint64, float64, util.Number:
+		return true
+	}
+	return false
+}
+
+// inEnum reports whether v is one of the values of enum.
+func inEnum(enum []apiextensionsv1.JSON, v any) bool {
+	// Compare in the JSON representation, where all numbers are float64.
+	vj, err := jsonValue(v)
+	if err != nil {
+		return false
+	}
+	for _, e := range enum {
+		var ej any
+		if err := json.Unmarshal(e.Raw, &ej); err != nil {
+			continue
+		}
+		if reflect.DeepEqual(ej, vj) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonValue returns v as it is unmarshaled from JSON.
+func jsonValue(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	return out, json.Unmarshal(b, &out)
+}
+
+// enumValues returns the values of enum for error messages, with strings unquoted.
+func enumValues(enum []apiextensionsv1.JSON) []string {
+	out := make([]string, 0, len(enum))
+	for _, e := range enum {
+		var s string
+		if err := json.Unmarshal(e.Raw, &s); err == nil {
+			out = append(out, s)
+			continue
+		}
+		out = append(out, string(e.Raw))
+	}
+	return out
+}
+
+// validateMetadata validates the metadata of obj as the API server does on creation. Objects with a namespace are
+// assumed to be namespaced and all others to be cluster scoped.
+func validateMetadata(obj map[string]any) field.ErrorList {
+	fldPath := field.NewPath("metadata")
+	m, ok := obj["metadata"].(map[string]any)
+	if !ok {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+	meta := &metav1.ObjectMeta{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, meta); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, m, err.Error())}
+	}
+	kind, _ := obj["kind"].(string)
+	return apivalidation.ValidateObjectMeta(meta, meta.Namespace != "", nameValidator(kind), fldPath)
+}
+
+// nameValidator returns the function that validates the names of objects of the given kind.
+func nameValidator(kind string) apivalidation.ValidateNameFunc {
+	switch kind {
+	case "Role", "ClusterRole", "RoleBinding", "ClusterRoleBinding":
+		// RBAC names only need to be valid path segments, as in system:controller:foo.
+		return path.ValidatePathSegmentName
+	case "Namespace":
+		return apivalidation.ValidateNamespaceName
+	case "Service":
+		return apivalidation.NameIsDNS1035Label
+	}
+	return apivalidation.NameIsDNSSubdomain
+}
@@ -0,0 +1,220 @@
+package openapi
+
+import (
+	"testing"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestValidateObject(t *testing.T) {
+	crds, err := object.ParseK8sObjectsFromYAMLManifest(`
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    plural: widgets
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            required: [size]
+            properties:
+              size:
+                type: integer
+              color:
+                type: string
+                enum: [red, green]
+              extra:
+                type: object
+                x-kubernetes-preserve-unknown-fields: true
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := FromManifestCRDs(crds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	providers := Providers{p, Builtin()}
+
+	tests := []struct {
+		desc string
+		obj  string
+		want []string
+	}{
+		{
+			desc: "valid deployment",
+			obj: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: d
+  namespace: ns
+  creationTimestamp: null
+  labels:
+    app: d
+spec:
+  replicas: 3
+  strategy:
+    rollingUpdate:
+      maxSurge: 25%
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1
+        resources:
+          limits:
+            cpu: 1
+            memory: 1Gi
+`,
+		},
+		{
+			desc: "wrong types",
+			obj: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: d
+spec:
+  replicas: three
+  paused: "yes"
+  strategy:
+    rollingUpdate:
+      maxSurge: [1]
+  template:
+    spec:
+      containers: app
+`,
+			want: []string{
+				`spec.paused: Invalid value: "yes": must be a boolean`,
+				`spec.replicas: Invalid value: "three": must be an integer`,
+				`spec.strategy.rollingUpdate.maxSurge: Invalid value: []interface {}{1}: must be an integer or a string`,
+				`spec.template.spec.containers: Invalid value: "app": must be an array`,
+			},
+		},
+		{
+			desc: "unknown fields",
+			obj: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: d
+spec:
+  replica: 3
+  template:
+    spec:
+      containers:
+      - name: app
+        imagePullPolicy: Always
+        foo: bar
+`,
+			want: []string{
+				"spec.replica: Forbidden: unknown field, did you mean `replicas`?",
+				"spec.template.spec.containers[0].foo: Forbidden: unknown field",
+			},
+		},
+		{
+			desc: "missing required fields",
+			obj: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: s
+spec:
+  ports:
+  - name: http
+`,
+			want: []string{"spec.ports[0].port: Required value"},
+		},
+		{
+			desc: "invalid metadata",
+			obj: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: Not_Valid
+  labels:
+    app: -bad-
+`,
+			want: []string{
+				`metadata.name: Invalid value: "Not_Valid": a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')`,
+				`metadata.labels: Invalid value: "-bad-": a valid label must be an empty string or consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyValue',  or 'my_value',  or '12345', regex used for validation is '(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?')`,
+			},
+		},
+		{
+			desc: "RBAC names",
+			obj: `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: system:controller:foo
+`,
+		},
+		{
+			desc: "custom resource",
+			obj: `
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: w
+  namespace: ns
+spec:
+  color: blue
+  extra:
+    anything: goes
+  shape: round
+`,
+			want: []string{
+				"spec.size: Required value",
+				`spec.color: Unsupported value: "blue": supported values: "red", "green"`,
+				"spec.shape: Forbidden: unknown field",
+			},
+		},
+		{
+			desc: "unknown kind",
+			obj: `
+apiVersion: example.com/v1
+kind: Gadget
+metadata:
+  name: g
+spec:
+  anything: goes
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			obj, err := object.ParseYAMLToK8sObject([]byte(tt.obj))
+			if err != nil {
+				t.Fatal(err)
+			}
+			gvk := schema.FromAPIVersionAndKind(obj.UnstructuredObject().GetAPIVersion(), obj.Kind)
+			errs := ValidateObject(providers.Schema(gvk), obj.Unstructured())
+			var got []string
+			for _, e := range errs {
+				got = append(got, e.Error())
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ValidateObject(): got %d errors:\n%v\nwant %d errors:\n%v", len(got), got, len(tt.want), tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ValidateObject(): error %d: got:\n%s\nwant:\n%s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
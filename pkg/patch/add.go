@@ -0,0 +1,103 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"github.com/stackrox/k8s-overlay-patch/pkg/types"
+	"github.com/stackrox/k8s-overlay-patch/pkg/util"
+)
+
+// validateAddition reports whether add sets either an object or a clone source, and has valid patches, whose conditions
+// it compiles into conditions.
+func validateAddition(addIndex int, add *types.K8sObjectAddition, conditions *conditionCache) error {
+	var errs util.Errors
+	switch {
+	case add.Object != nil && add.CloneFrom != nil:
+		errs = util.AppendErr(errs, fmt.Errorf("object and cloneFrom cannot be used together in add %d", addIndex))
+	case add.Object == nil && add.CloneFrom == nil:
+		errs = util.AppendErr(errs, fmt.Errorf("add %d must set object or cloneFrom", addIndex))
+	case add.Object != nil && add.Name != "":
+		errs = util.AppendErr(errs, fmt.Errorf("name can only be used with cloneFrom in add %d", addIndex))
+	case add.CloneFrom != nil && (add.CloneFrom.Kind == "" || add.CloneFrom.Name == "" || add.Name == ""):
+		errs = util.AppendErr(errs, fmt.Errorf("add %d must set the kind and name of cloneFrom and the name of the clone", addIndex))
+	}
+	return util.AppendErr(errs, validatePatches(fmt.Sprintf("add %d", addIndex), add.Patches, conditions)).ToError()
+}
+
+// add adds the objects of adds to the manifest, after applying their patches. Invalid additions are reported and
+// skipped.
+func (m *manifestPatcher) add(adds []*types.K8sObjectAddition) util.Errors {
+	var errs util.Errors
+	for i, add := range adds {
+		if err := validateAddition(i, add, m.o.conditions); err != nil {
+			errs = util.AppendErr(errs, err)
+			continue
+		}
+		obj, err := m.newObject(add)
+		if err != nil {
+			errs = util.AppendErr(errs, fmt.Errorf("add %d: %v", i, err))
+			continue
+		}
+		if m.exists(obj) {
+			errs = util.AppendErr(errs, fmt.Errorf("add %d: %s already exists in manifest", i, obj.Hash()))
+			continue
+		}
+		if len(add.Patches) > 0 {
+			y, errs2 := applyPatches(obj, add.Patches, m.defaultNamespace, m.o, m.sourceValue)
+			if len(errs2) > 0 {
+				errs = util.AppendErrs(errs, errs2)
+				continue
+			}
+			if obj, err = object.ParseYAMLToK8sObject([]byte(y)); err != nil {
+				errs = util.AppendErr(errs, fmt.Errorf("add %d: %v", i, err))
+				continue
+			}
+		}
+		m.objs = append(m.objs, obj)
+	}
+	return errs
+}
+
+// newObject returns the object add adds, before its patches are applied.
+func (m *manifestPatcher) newObject(add *types.K8sObjectAddition) (*object.K8sObject, error) {
+	if add.CloneFrom == nil {
+		j, err := json.Marshal(add.Object)
+		if err != nil {
+			return nil, err
+		}
+		obj, err := object.ParseJSONToK8sObject(j)
+		if err != nil {
+			return nil, err
+		}
+		if obj.Kind == "" || obj.Name == "" {
+			return nil, fmt.Errorf("object must set kind and metadata.name")
+		}
+		return obj, nil
+	}
+
+	src := m.find(add.CloneFrom.Kind, add.CloneFrom.Name)
+	switch len(src) {
+	case 0:
+		return nil, fmt.Errorf("cloneFrom %s:%s not found in manifest", add.CloneFrom.Kind, add.CloneFrom.Name)
+	case 1:
+	default:
+		return nil, fmt.Errorf("cloneFrom %s:%s matches multiple objects in manifest:\n%s",
+			add.CloneFrom.Kind, add.CloneFrom.Name, strings.Join(src.Keys(), "\n"))
+	}
+	u := src[0].UnstructuredObject().DeepCopy()
+	u.SetName(add.Name)
+	return object.NewK8sObject(u, nil, nil), nil
+}
+
+// exists reports whether the manifest has an object with the kind, namespace and name of obj.
+func (m *manifestPatcher) exists(obj *object.K8sObject) bool {
+	for _, o := range m.objs {
+		if o.Hash() == obj.Hash() {
+			return true
+		}
+	}
+	return false
+}
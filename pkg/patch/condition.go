@@ -0,0 +1,164 @@
+package patch
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/google/cel-go/cel"
+	"github.com/stackrox/k8s-overlay-patch/pkg/tpath"
+	"github.com/stackrox/k8s-overlay-patch/pkg/types"
+	"github.com/stackrox/k8s-overlay-patch/pkg/util"
+	yaml2 "gopkg.in/yaml.v3"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// celCostLimit is the cost, as CEL estimates it, a condition expression may take to evaluate against an object before
+// it is aborted. It is the per expression limit of the validation rules of CustomResourceDefinitions.
+const celCostLimit = 1000000
+
+// conditionCache holds the compiled regular expressions and CEL programs of the conditions of the patches of one
+// YAMLManifestPatch call, by their text, so that each is compiled once however many objects and paths it is evaluated
+// for. Patches with templated values are copied when they are applied, so the text rather than the condition is the
+// key.
+type conditionCache struct {
+	regexes  map[string]*regexp.Regexp
+	programs map[string]cel.Program
+}
+
+func newConditionCache() *conditionCache {
+	return &conditionCache{regexes: make(map[string]*regexp.Regexp), programs: make(map[string]cel.Program)}
+}
+
+// regex returns the compiled matches expression expr.
+func (c *conditionCache) regex(expr string) (*regexp.Regexp, error) {
+	if re, ok := c.regexes[expr]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid matches expression %q: %v", expr, err)
+	}
+	c.regexes[expr] = re
+	return re, nil
+}
+
+// program returns the compiled CEL expression expr, see compileCEL.
+func (c *conditionCache) program(expr string) (cel.Program, error) {
+	if prg, ok := c.programs[expr]; ok {
+		return prg, nil
+	}
+	prg, err := compileCEL(expr)
+	if err != nil {
+		return nil, err
+	}
+	c.programs[expr] = prg
+	return prg, nil
+}
+
+// validateCondition reports whether the path, regular expression and CEL expression of the condition are valid, and
+// compiles the latter two into conditions. syntax is the path syntax of the patch.
+func validateCondition(syntax string, cond *types.K8sObjectOverlayPatchCondition, conditions *conditionCache) error {
+	if cond.Path != "" {
+		if err := validatePath(syntax, cond.Path); err != nil {
+			return err
+		}
+	}
+	if cond.Matches != "" {
+		if _, err := conditions.regex(cond.Matches); err != nil {
+			return err
+		}
+	}
+	if cond.Expression != "" {
+		if _, err := conditions.program(cond.Expression); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// conditionHolds evaluates cond against the object tree in root. path is the concrete path the patch is applied to,
+// used when the condition does not specify its own path, and syntax the path syntax of the patch. The expressions of
+// cond are compiled with conditions.
+func conditionHolds(root map[any]any, path util.Path, syntax string, cond *types.K8sObjectOverlayPatchCondition, conditions *conditionCache) (bool, error) {
+	if cond.Path != "" {
+		var err error
+		if path, err = util.ParsePathSyntax(syntax, cond.Path); err != nil {
+			return false, err
+		}
+	}
+	if cond.Exists != nil || cond.Equals != nil || cond.Matches != "" {
+		nc, found, err := tpath.FindPathContext(root, path)
+		if err != nil {
+			return false, err
+		}
+		if cond.Exists != nil && *cond.Exists != found {
+			return false, nil
+		}
+		if !found {
+			return cond.Equals == nil && cond.Matches == "", nil
+		}
+		node := nc.Node
+		if p, ok := node.(*any); ok {
+			node = *p
+		}
+		if cond.Equals != nil && fmt.Sprint(node) != *cond.Equals {
+			return false, nil
+		}
+		if cond.Matches != "" {
+			re, err := conditions.regex(cond.Matches)
+			if err != nil {
+				return false, err
+			}
+			if !re.MatchString(fmt.Sprint(node)) {
+				return false, nil
+			}
+		}
+	}
+	if cond.Expression != "" {
+		prg, err := conditions.program(cond.Expression)
+		if err != nil {
+			return false, err
+		}
+		return evalCEL(root, cond.Expression, prg)
+	}
+	return true, nil
+}
+
+// compileCEL compiles expr into a program that takes the object as self and returns a bool, and is aborted if it
+// exceeds celCostLimit.
+func compileCEL(expr string) (cel.Program, error) {
+	env, err := cel.NewEnv(cel.Variable("self", cel.DynType))
+	if err != nil {
+		return nil, err
+	}
+	ast, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		return nil, fmt.Errorf("invalid expression %q: %v", expr, iss.Err())
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("expression %q must evaluate to bool, got %s", expr, ast.OutputType())
+	}
+	return env.Program(ast, cel.CostLimit(celCostLimit))
+}
+
+// evalCEL evaluates prg, the compiled CEL expression expr, with self bound to the object tree in root.
+func evalCEL(root map[any]any, expr string, prg cel.Program) (bool, error) {
+	// Round trip through YAML to get a JSON compatible tree with int64 and float64 numbers.
+	by, err := yaml2.Marshal(root)
+	if err != nil {
+		return false, err
+	}
+	self := make(map[string]any)
+	if err := k8syaml.Unmarshal(by, &self); err != nil {
+		return false, err
+	}
+	out, _, err := prg.Eval(map[string]any{"self": self})
+	if err != nil {
+		return false, fmt.Errorf("evaluating expression %q: %v", expr, err)
+	}
+	b, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q must evaluate to bool, got %T", expr, out.Value())
+	}
+	return b, nil
+}
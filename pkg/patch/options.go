@@ -0,0 +1,110 @@
+package patch
+
+import (
+	"github.com/stackrox/k8s-overlay-patch/pkg/openapi"
+	"github.com/stackrox/k8s-overlay-patch/pkg/transform"
+	"github.com/stackrox/k8s-overlay-patch/pkg/types"
+)
+
+// Option configures YAMLManifestPatch.
+type Option func(*options)
+
+type options struct {
+	// strictPaths fails patches whose paths have missing intermediate nodes instead of creating them.
+	strictPaths bool
+	// verbose lists all objects in errors for overlays that do not match any object.
+	verbose bool
+	// schemas converts patch values to the types declared by the schemas of the patched objects, if set.
+	schemas openapi.Provider
+	// validation validates patched objects against their schemas, if set.
+	validation openapi.Provider
+	// vars are the variables of templated patch values.
+	vars map[string]any
+	// transformers are applied to the manifest after the overlays.
+	transformers []transform.Transformer
+	// additions are objects added to the manifest before the overlays are applied.
+	additions []*types.K8sObjectAddition
+	// conditions are the compiled expressions of the conditions of the patches.
+	conditions *conditionCache
+	// removalReporters are called with the key of each object removed from the manifest.
+	removalReporters []func(key string)
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{conditions: newConditionCache()}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithStrictPaths sets whether patch paths must exist up to their leaf. In strict mode only the final element of a
+// path may be created, and a patch whose intermediate nodes are missing fails with an error that suggests similarly
+// named existing keys. Without it, which is the default, missing intermediate map entries are created, so a typo in a
+// path silently adds a new subtree. Overlays from a custom resource should be patched in strict mode.
+func WithStrictPaths(strict bool) Option {
+	return func(o *options) {
+		o.strictPaths = strict
+	}
+}
+
+// WithVerboseErrors sets whether errors for overlays that do not match any object list the keys of all objects in the
+// manifest, in addition to the suggested similar objects.
+func WithVerboseErrors(verbose bool) Option {
+	return func(o *options) {
+		o.verbose = verbose
+	}
+}
+
+// WithSchemaProvider converts patch values to the types the schemas of p declare for the fields they are written to,
+// and fails patches whose values cannot be converted. The schemas of the CRDs in the manifest are used in addition to
+// those of p. Use openapi.Builtin for the built-in Kubernetes kinds. Without it, the types of values are guessed from
+// their YAML representation. Verbatim values are always written as strings.
+func WithSchemaProvider(p openapi.Provider) Option {
+	return func(o *options) {
+		o.schemas = p
+	}
+}
+
+// WithValidation validates every object of the output that an overlay, an addition or a transformer changed, after
+// the transformers are applied, against its schema from p or from the CRDs in the manifest. Unknown fields, values of the wrong type or not in an enum and missing required fields are
+// reported, as are invalid names, labels and annotations. Objects of kinds without a schema only have their metadata
+// validated. Each invalid object results in an *ObjectValidationError, see ObjectValidationErrors. Use openapi.Builtin
+// for the built-in Kubernetes kinds.
+func WithValidation(p openapi.Provider) Option {
+	return func(o *options) {
+		o.validation = p
+	}
+}
+
+// WithVars sets the variables available to templated patch values as .Vars, such as the vars of a patch file.
+func WithVars(vars map[string]any) Option {
+	return func(o *options) {
+		o.vars = vars
+	}
+}
+
+// WithTransformers applies ts in order to the objects of the manifest after the overlays are applied, such as
+// transform.ConfigChecksums. It can be given several times to add transformers.
+func WithTransformers(ts ...transform.Transformer) Option {
+	return func(o *options) {
+		o.transformers = append(o.transformers, ts...)
+	}
+}
+
+// WithAdditions adds the objects of adds to the manifest before the overlays are applied, so that overlays can target
+// them. Added objects are appended to the manifest in order, and can be cloned by later additions.
+func WithAdditions(adds []*types.K8sObjectAddition) Option {
+	return func(o *options) {
+		o.additions = adds
+	}
+}
+
+// WithRemovalReporter calls report with the key of each object that an overlay with remove set omits from the output
+// manifest, in the kind:namespace:name form of object.Hash, in manifest order. Each WithRemovalReporter option adds a
+// reporter, so that wrappers of YAMLManifestPatch can track removals without replacing the reporter of their caller.
+func WithRemovalReporter(report func(key string)) Option {
+	return func(o *options) {
+		o.removalReporters = append(o.removalReporters, report)
+	}
+}
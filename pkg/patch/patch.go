@@ -15,9 +15,18 @@
 /*
 Package patch implements a simple patching mechanism for k8s resources.
 Paths are specified in the form a.b.c.[key:value].d.[list_entry_value], where:
-  - [key:value] selects a list entry in list c which contains an entry with key:value
+  - [key:value] selects a list entry in list c which contains an entry with key:value. Several pairs can be combined
+    as [key1:value1,key2:value2] to select the entry that matches all of them, and keys can refer to nested entries
+    as in [key1.key2:value].
   - [list_entry_value] selects a list entry in list d which is a regex match of list_entry_value.
 
+Map keys containing '.' or other special characters can be quoted, as in metadata.annotations["app.kubernetes.io/name"].
+Inside quotes only '"' and '\' must be escaped, as \" and \\.
+
+Instead of ':', a key/value pair can use a match operator: [key=value] matches exactly, [key^=prefix] by prefix,
+[key*=glob] by glob pattern and [key~=regex] by a regular expression that must match the entire value. List entries
+can be selected the same way with [=value], [^=prefix], [*=glob] and [~=regex].
+
 Some examples are given below. Given a resource:
 
 	kind: Deployment
@@ -61,6 +70,26 @@ values and list entries can be added, modifed or deleted.
     name: grpc-xds
     protocol: TCP
 
+5. set the name of the TCP port named grpc
+
+	path: spec.ports.[name:grpc,protocol:TCP].name
+	value: grpc-tcp
+
+6. set the key of the env var sourced from the secret foo
+
+	path: spec.template.spec.containers.[name:app].env.[valueFrom.secretKeyRef.name:foo].valueFrom.secretKeyRef.key
+	value: password
+
+7. set an annotation whose key contains dots and slashes
+
+	path: metadata.annotations["checksum.example.com//config"]
+	value: abc123
+
+8. set the value of the --log-level argument of all containers named sidecar-*
+
+	path: spec.template.spec.containers.[name*=sidecar-*].args.[^=--log-level=]
+	value: --log-level=debug
+
 # DELETE
 
 1. Delete container with name: n1
@@ -88,10 +117,164 @@ just an example which normally is greater than the most of the lists used.
 	value:
 	  new_attr: v3
 
+# WILDCARDS
+
+[*] selects every element of a list and * every entry of a map. The patch is applied to each selected node.
+Path elements after the last wildcard are resolved for every selected node like those of any other path: missing
+nodes are created, or fail the patch with strict paths. A map key named * is written as a quoted key, a["*"], to
+address it rather than every entry.
+
+1. Set the pull policy of all containers
+
+	path: spec.template.spec.containers.[*].imagePullPolicy
+	value: Always
+
+2. Set the memory of both requests and limits of the app container
+
+	path: spec.template.spec.containers.[name:app].resources.*.memory
+	value: 1Gi
+
+3. Delete the env of all init containers
+
+	path: spec.template.spec.initContainers.[*].env
+
+# JSONPATH
+
+Paths can also be written in kubectl JSONPath syntax. Paths starting with $ or { are JSONPath, and pathSyntax: jsonpath
+selects it for any path. Filters compile to selectors, so [?(@.name=="app")] is the same as [name=app]. Only
+comparisons with == joined by && are supported in filters.
+
+1. Set the image of the app container
+
+	path: spec.template.spec.containers[?(@.name=="app")].image
+	pathSyntax: jsonpath
+	value: app:2
+
+2. Set the verbosity argument of the app container
+
+	path: $.spec.template.spec.containers[?(@.name=="app")].args[?(@=="--v=1")]
+	value: --v=2
+
+# CONDITIONS
+
+A patch can be made conditional on the object it is applied to with when. All conditions that are set must hold,
+otherwise the patch is skipped. path defaults to the path of the patch.
+
+1. Set a memory limit only if none is set
+
+	path: spec.template.spec.containers.[name:app].resources.limits.memory
+	value: 1Gi
+	when:
+	  exists: false
+
+2. Only patch images from our registry
+
+	path: spec.template.spec.containers.[name:app].imagePullPolicy
+	value: Always
+	when:
+	  path: spec.template.spec.containers.[name:app].image
+	  matches: ^registry.example.com/
+
+3. Arbitrary CEL expressions, with the object available as self
+
+	when:
+	  expression: self.spec.replicas > 1
+
+Expressions that exceed the cost limit of CustomResourceDefinition validation rules fail the patch.
+
+# VALUE TYPES
+
+Values are parsed as YAML, except that strings are only unmarshaled into maps if they look like one. Numbers are
+written as given, so large integers keep their precision and decimals such as 1.10 their trailing zeros. type sets the
+type of the value explicitly instead: string, int, bool, float, yaml, json, base64 or null. base64 writes the base64
+encoding of the value, and null writes an explicit null instead of deleting the node.
+
+1. Set an annotation to a string that looks like YAML
+
+	path: metadata.annotations.note
+	value: "note: keep"
+	type: string
+
+2. Set a Secret key from plain text
+
+	path: data.password
+	value: hunter2
+	type: base64
+
+# TEMPLATES
+
+With template: true, value or verbatim is a Go text/template that is executed for the object the patch is applied
+to. .Object is the object before the patches of the overlay are applied, .Namespace its namespace or the default
+namespace, and .Vars the variables set with WithVars. Missing map keys are empty, and the functions default, quote,
+b64enc, toYaml and sha256 are available. The result is used as if it were the value written in the patch.
+
+1. Name a ServiceAccount after the object
+
+	path: spec.template.spec.serviceAccountName
+	value: "{{ .Object.metadata.name }}-sa"
+	template: true
+
+2. Set a label from the variables, with a default
+
+	path: metadata.labels.team
+	value: '{{ .Vars.team | default "platform" }}'
+	template: true
+
+# COPYING VALUES
+
+valueFrom copies the value at a path of another object of the manifest, matched by kind and name like the objects of
+overlays. Values are copied from the source object after its own overlays are applied, and patches that copy values
+from each other in a cycle fail. A patch can also copy a value from the object it patches, which is taken before the
+overlays are applied.
+
+1. Set an env var to the port of a Service
+
+	path: spec.template.spec.containers.[name:app].env.[name:PORT].value
+	valueFrom:
+	  kind: Service
+	  name: web
+	  path: spec.ports.[name:http].port
+
+# ADDING OBJECTS
+
+WithAdditions adds objects to the manifest, written inline with object or cloned from an object of the manifest with
+cloneFrom under a new name. The patches of an addition are applied to it, and the added objects are appended to the
+output, where overlays match them like the objects of the manifest. Adding an object that is already in the manifest
+fails.
+
+1. Clone a Service
+
+	cloneFrom:
+	  kind: Service
+	  name: web
+	name: web-internal
+	patches:
+	- path: spec.type
+	  value: ClusterIP
+
+# REMOVING OBJECTS
+
+An overlay with remove: true and no patches removes the object it matches from the output manifest. With a label
+selector, it removes all objects in the default namespace or without a namespace whose labels match, restricted to the
+kind and name of the overlay if they are set, and fails only if no object matches. WithRemovalReporter reports the
+removed objects.
+
+1. Remove all ServiceMonitors of a component
+
+	kind: ServiceMonitor
+	remove: true
+	selector:
+	  matchLabels:
+	    app.kubernetes.io/component: metrics
+
 *NOTES*
 - Due to loss of string quoting during unmarshaling, keys and values should not be string quoted, even if they appear
 that way in the object being patched.
-- [key:value] treats ':' as a special separator character. Any ':' in the key or value string must be escaped as \:.
+- [key:value] treats ':' and ',' as special separator characters. Any ':' or ',' in the key or value string must be
+escaped as \: or \,. Any '.' in the key string must be escaped as \. to not be treated as a nested key.
+- Outside of quotes and [], a '.' that is part of a key can also be escaped as \., as in a\.b.c.
+- Glob patterns and regular expressions are used as written, escapes included. A ',' in them must still be escaped
+as \, which both treat as a literal ','.
 */
 package patch
 
@@ -103,11 +286,12 @@ import (
 	"strings"
 
 	"github.com/go-logr/logr"
-	"github.com/stackrox/k8s-overlay-patch/pkg/helm"
 	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"github.com/stackrox/k8s-overlay-patch/pkg/openapi"
 	"github.com/stackrox/k8s-overlay-patch/pkg/tpath"
+	"github.com/stackrox/k8s-overlay-patch/pkg/transform"
 	"github.com/stackrox/k8s-overlay-patch/pkg/util"
-	"google.golang.org/protobuf/types/known/structpb"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 )
 
 // var scope = log.RegisterScope("patch", "patch")
@@ -115,6 +299,9 @@ var scope = logr.FromContextOrDiscard(context.Background()).WithName("patch")
 
 // overlayMatches reports whether obj matches the overlay for either the default namespace or no namespace (cluster scope).
 func overlayMatches(overlay *types.K8sObjectOverlay, obj *object.K8sObject, defaultNamespace string) bool {
+	if overlay.Selector != nil {
+		return selectorMatches(overlay, obj, defaultNamespace)
+	}
 	oh := obj.Hash()
 	if oh == object.Hash(overlay.Kind, defaultNamespace, overlay.Name) ||
 		oh == object.Hash(overlay.Kind, "", overlay.Name) {
@@ -125,8 +312,10 @@ func overlayMatches(overlay *types.K8sObjectOverlay, obj *object.K8sObject, defa
 
 // YAMLManifestPatch patches a base YAML in the given namespace with a list of overlays.
 // Each overlay has the format described in the K8sObjectOverlay definition.
-// It returns the patched manifest YAML.
-func YAMLManifestPatch(baseYAML string, defaultNamespace string, overlays []*types.K8sObjectOverlay) (string, error) {
+// It returns the patched manifest YAML. If all overlays are applied, the transformers set with WithTransformers are
+// applied to the patched objects.
+func YAMLManifestPatch(baseYAML string, defaultNamespace string, overlays []*types.K8sObjectOverlay, opts ...Option) (string, error) {
+	o := newOptions(opts)
 	var ret strings.Builder
 	var errs util.Errors
 	objs, err := object.ParseK8sObjectsFromYAMLManifest(baseYAML)
@@ -134,63 +323,207 @@ func YAMLManifestPatch(baseYAML string, defaultNamespace string, overlays []*typ
 		return "", err
 	}
 	for i, overlay := range overlays {
-		errs = util.AppendErr(errs, validateOverlay(i, overlay))
+		errs = util.AppendErr(errs, validateOverlay(i, overlay, o.conditions))
+	}
+	if o.schemas != nil || o.validation != nil {
+		crds, err := openapi.FromManifestCRDs(objs)
+		errs = util.AppendErr(errs, err)
+		if o.schemas != nil {
+			o.schemas = openapi.Providers{crds, o.schemas}
+		}
+		if o.validation != nil {
+			o.validation = openapi.Providers{crds, o.validation}
+		}
 	}
 
-	matches := make(map[*types.K8sObjectOverlay]object.K8sObjects)
+	m := &manifestPatcher{
+		objs:             objs,
+		overlays:         overlays,
+		defaultNamespace: defaultNamespace,
+		o:                o,
+		matches:          make(map[*types.K8sObjectOverlay]object.K8sObjects),
+		results:          make(map[*object.K8sObject]*patchResult),
+	}
+	errs = util.AppendErrs(errs, m.add(o.additions))
+	// unchanged are the objects of the manifest no overlay matched, as JSON, which are not validated.
+	unchanged := make(map[string]bool)
 	// Try to apply the defined overlays.
-	for _, obj := range objs {
-		oy, err := obj.YAML()
-		if err != nil {
-			errs = util.AppendErr(errs, fmt.Errorf("object to YAML error (%s) for base object: \n%s", err, obj.YAMLDebugString()))
+	for i, obj := range m.objs {
+		r := m.patch(obj)
+		errs = util.AppendErrs(errs, r.errs)
+		if r.removed {
+			for _, report := range o.removalReporters {
+				report(obj.Hash())
+			}
+			continue
+		}
+		if r.yaml == "" {
 			continue
 		}
-		oys := string(oy)
-		for _, overlay := range overlays {
-			if overlayMatches(overlay, obj, defaultNamespace) {
-				matches[overlay] = append(matches[overlay], obj)
-				var errs2 util.Errors
-				oys, errs2 = applyPatches(obj, overlay.Patches)
-				errs = util.AppendErrs(errs, errs2)
+		if !r.patched && i < len(objs) {
+			if j, err := canonicalJSON(obj); err == nil {
+				unchanged[j] = true
 			}
 		}
-		if _, err := ret.WriteString(oys + helm.YAMLSeparator); err != nil {
+		if _, err := ret.WriteString(r.yaml + object.YAMLSeparator); err != nil {
 			errs = util.AppendErr(errs, fmt.Errorf("writeString: %s", err))
 		}
 	}
 
 	for _, overlay := range overlays {
-		// Each overlay should have exactly one match in the output manifest.
+		// Each overlay should have exactly one match in the output manifest, or at least one if it has a selector.
 		switch {
-		case len(matches[overlay]) == 0:
+		case len(m.matches[overlay]) == 0:
 			if overlay.Optional {
 				scope.V(2).Info("overlay for %s:%s is optional and does not match any object in output manifest", overlay.Kind, overlay.Name)
 				continue
 			}
-			errs = util.AppendErr(errs, fmt.Errorf("overlay for %s:%s does not match any object in output manifest. Available objects are:\n%s",
-				overlay.Kind, overlay.Name, strings.Join(objs.Keys(), "\n")))
-		case len(matches[overlay]) > 1:
+			if overlay.Selector != nil {
+				errs = util.AppendErr(errs, unmatchedSelectorError(overlay))
+				continue
+			}
+			errs = util.AppendErr(errs, unmatchedOverlayError(overlay, m.objs, defaultNamespace, o.verbose))
+		case len(m.matches[overlay]) > 1 && overlay.Selector == nil:
 			errs = util.AppendErr(errs, fmt.Errorf("overlay for %s:%s matches multiple objects in output manifest:\n%s",
-				overlay.Kind, overlay.Name, strings.Join(objs.Keys(), "\n")))
+				overlay.Kind, overlay.Name, strings.Join(m.matches[overlay].Keys(), "\n")))
+		}
+	}
+
+	out := ret.String()
+	if len(o.transformers) > 0 && len(errs) == 0 {
+		if out, err = transform.Manifest(out, o.transformers...); err != nil {
+			return "", err
+		}
+	}
+	if o.validation != nil {
+		errs = util.AppendErrs(errs, validateChangedObjects(out, unchanged, o.validation))
+	}
+	return out, errs.ToError()
+}
+
+// manifestPatcher applies overlays to the objects of a manifest. Objects are patched when they are first needed, either
+// to be written to the output or as the source of a value copied with valueFrom, so that copied values are taken from
+// patched objects.
+type manifestPatcher struct {
+	objs             object.K8sObjects
+	overlays         []*types.K8sObjectOverlay
+	defaultNamespace string
+	o                *options
+	// matches are the objects each overlay matched.
+	matches map[*types.K8sObjectOverlay]object.K8sObjects
+	// results are the objects that have been patched.
+	results map[*object.K8sObject]*patchResult
+	// inProgress are the objects being patched, outermost first, to detect cycles of valueFrom sources.
+	inProgress []*object.K8sObject
+}
+
+// patchResult is the result of patching an object.
+type patchResult struct {
+	// yaml is the patched object, or empty if it could not be rendered.
+	yaml string
+	// patched is whether an overlay matched the object.
+	patched bool
+	// removed is whether an overlay removes the object from the output manifest.
+	removed bool
+	errs    util.Errors
+}
+
+// patch applies the matching overlays to obj, once.
+func (m *manifestPatcher) patch(obj *object.K8sObject) *patchResult {
+	if r, ok := m.results[obj]; ok {
+		return r
+	}
+	m.inProgress = append(m.inProgress, obj)
+	defer func() { m.inProgress = m.inProgress[:len(m.inProgress)-1] }()
+
+	r := &patchResult{}
+	m.results[obj] = r
+	oy, err := obj.YAML()
+	if err != nil {
+		r.errs = util.NewErrs(fmt.Errorf("object to YAML error (%s) for base object: \n%s", err, obj.YAMLDebugString()))
+		return r
+	}
+	oys := string(oy)
+	for _, overlay := range m.overlays {
+		if overlayMatches(overlay, obj, m.defaultNamespace) {
+			m.matches[overlay] = append(m.matches[overlay], obj)
+			if overlay.Remove {
+				r.removed = true
+				continue
+			}
+			var errs2 util.Errors
+			oys, errs2 = applyPatches(obj, overlay.Patches, m.defaultNamespace, m.o, m.sourceValue)
+			r.errs = util.AppendErrs(r.errs, errs2)
+			r.patched = true
 		}
 	}
+	r.yaml = oys
+	return r
+}
 
-	return ret.String(), errs.ToError()
+// validateOverlay validates overlay, the overlayIndex-th overlay, and compiles the conditions of its patches into
+// conditions.
+func validateOverlay(overlayIndex int, overlay *types.K8sObjectOverlay, conditions *conditionCache) error {
+	errs := util.NewErrs(validateRemoval(overlayIndex, overlay))
+	return util.AppendErr(errs, validatePatches(fmt.Sprintf("overlay %d", overlayIndex), overlay.Patches, conditions)).ToError()
 }
 
-func validateOverlay(overlayIndex int, overlay *types.K8sObjectOverlay) error {
+// validatePatches validates patches, which belong to owner, such as overlay 1, in error messages, and compiles their
+// conditions into conditions.
+func validatePatches(owner string, patches []*types.K8sObjectOverlayPatch, conditions *conditionCache) error {
 	var errs util.Errors
-	for patchIndex, patch := range overlay.Patches {
+	for patchIndex, patch := range patches {
 		if patch.Value != "" && patch.Verbatim != "" {
-			errs = util.AppendErr(errs, fmt.Errorf("value and verbatim cannot be used together in overlay %d patch %d", overlayIndex, patchIndex))
+			errs = util.AppendErr(errs, fmt.Errorf("value and verbatim cannot be used together in %s patch %d", owner, patchIndex))
+		}
+		if patch.Type != "" {
+			if patch.Verbatim != "" {
+				errs = util.AppendErr(errs, fmt.Errorf("type and verbatim cannot be used together in %s patch %d", owner, patchIndex))
+			}
+			if _, err := typedValue(patch.Type, patch.Value); err != nil && !patch.Template {
+				errs = util.AppendErr(errs, fmt.Errorf("%s patch %d: %v", owner, patchIndex, err))
+			}
+		}
+		if patch.Template {
+			for _, text := range []string{patch.Value, patch.Verbatim} {
+				if _, err := parseTemplate(text); err != nil {
+					errs = util.AppendErr(errs, fmt.Errorf("%s patch %d: %v", owner, patchIndex, err))
+				}
+			}
+		}
+		if patch.ValueFrom != nil {
+			if patch.Value != "" || patch.Verbatim != "" || patch.Type != "" {
+				errs = util.AppendErr(errs, fmt.Errorf("valueFrom cannot be used together with value, verbatim or type in %s patch %d", owner, patchIndex))
+			}
+			if err := validateSource(patch.PathSyntax, patch.ValueFrom); err != nil {
+				errs = util.AppendErr(errs, fmt.Errorf("%s patch %d: %v", owner, patchIndex, err))
+			}
+		}
+		if err := validatePath(patch.PathSyntax, patch.Path); err != nil {
+			errs = util.AppendErr(errs, fmt.Errorf("%s patch %d: %v", owner, patchIndex, err))
+		}
+		if patch.When != nil {
+			if err := validateCondition(patch.PathSyntax, patch.When, conditions); err != nil {
+				errs = util.AppendErr(errs, fmt.Errorf("%s patch %d: %v", owner, patchIndex, err))
+			}
 		}
 	}
 	return errs.ToError()
 }
 
+// validatePath reports whether path parses in the given syntax and its selectors have valid patterns.
+func validatePath(syntax, path string) error {
+	p, err := util.ParsePathSyntax(syntax, path)
+	if err != nil {
+		return err
+	}
+	return util.ValidatePath(p)
+}
+
 // applyPatches applies the given patches against the given object. It returns the resulting patched YAML if successful,
-// or a list of errors otherwise.
-func applyPatches(base *object.K8sObject, patches []*types.K8sObjectOverlayPatch) (outYAML string, errs util.Errors) {
+// or a list of errors otherwise. Templated values are expanded for the object, with defaultNamespace as its namespace
+// if it has none, and values copied from other objects are resolved with source.
+func applyPatches(base *object.K8sObject, patches []*types.K8sObjectOverlayPatch, defaultNamespace string, o *options, source sourceFunc) (outYAML string, errs util.Errors) {
 	bo := make(map[any]any)
 	by, err := base.YAML()
 	if err != nil {
@@ -201,34 +534,50 @@ func applyPatches(base *object.K8sObject, patches []*types.K8sObjectOverlayPatch
 	if err != nil {
 		return "", util.NewErrs(err)
 	}
+	var objSchema *apiextensionsv1.JSONSchemaProps
+	if o.schemas != nil {
+		objSchema = o.schemas.Schema(base.GroupVersionKind())
+	}
+	data := newTemplateData(base, defaultNamespace, o.vars)
 	for _, p := range patches {
-		var value interface{}
-		var tryUnmarshal bool
-		if p.Verbatim != "" && p.Value == "" {
-			value = p.Verbatim
-			tryUnmarshal = false
-		} else {
-			var v = &structpb.Value{}
-			if err := util.UnmarshalWithJSONPB(p.Value, v, false); err != nil {
-				errs = util.AppendErr(errs, err)
+		p, err := expandTemplate(p, data)
+		if err != nil {
+			errs = util.AppendErr(errs, err)
+			continue
+		}
+		var from any
+		if p.ValueFrom != nil {
+			if from, err = source(p.PathSyntax, p.ValueFrom); err != nil {
+				errs = util.AppendErr(errs, fmt.Errorf("path %s: %v", p.Path, err))
 				continue
 			}
-			value = v.AsInterface()
-			tryUnmarshal = true
+		}
+		value, _, err := patchValue(p, from)
+		if err != nil {
+			errs = util.AppendErr(errs, err)
+			continue
 		}
 		if strings.TrimSpace(p.Path) == "" {
 			scope.V(2).Info("skipping empty path", "value", value)
 			continue
 		}
-		scope.Info("applying", "path", p.Path, "value", value)
-		inc, _, err := tpath.GetPathContext(bo, util.PathFromString(p.Path), true)
+		path, err := util.ParsePathSyntax(p.PathSyntax, p.Path)
 		if err != nil {
 			errs = util.AppendErr(errs, err)
 			continue
 		}
-		err = tpath.WritePathContext(inc, value, false, tryUnmarshal)
+		paths, err := tpath.ExpandPath(bo, path)
 		if err != nil {
 			errs = util.AppendErr(errs, err)
+			continue
+		}
+		if len(paths) == 0 {
+			scope.V(2).Info("skipping patch, wildcard path matches no nodes", "path", p.Path)
+			continue
+		}
+		// Apply in reverse order so that deleting list elements does not shift the indexes of the remaining paths.
+		for i := len(paths) - 1; i >= 0; i-- {
+			errs = util.AppendErr(errs, applyPatch(bo, paths[i], p, from, o, objSchema))
 		}
 	}
 	var out strings.Builder
@@ -240,3 +589,73 @@ func applyPatches(base *object.K8sObject, patches []*types.K8sObjectOverlayPatch
 	}
 	return out.String(), errs
 }
+
+// applyPatch applies p at the given concrete path of the object tree in bo, with from as the value of its valueFrom
+// source. If objSchema is set, the value is converted to the type it declares for path.
+func applyPatch(bo map[any]any, path util.Path, p *types.K8sObjectOverlayPatch, from any, o *options, objSchema *apiextensionsv1.JSONSchemaProps) error {
+	if p.When != nil {
+		holds, err := conditionHolds(bo, path, p.PathSyntax, p.When, o.conditions)
+		if err != nil {
+			return err
+		}
+		if !holds {
+			scope.V(2).Info("skipping patch, condition does not hold", "path", path.String())
+			return nil
+		}
+	}
+	// Decode the value for every path, so that paths expanded from wildcards do not share map or list values.
+	value, tryUnmarshal, err := patchValue(p, from)
+	if err != nil {
+		return err
+	}
+	// Copied values have the types of their source, which may differ from those of path.
+	if s := openapi.Lookup(objSchema, path); s != nil && s.Type != "" && (tryUnmarshal || p.ValueFrom != nil) {
+		switch value.(type) {
+		case map[string]any, []any:
+			if s.Type == "string" && tryUnmarshal {
+				// A string field holds the text of the value, even if it looks like YAML.
+				value = p.Value
+			}
+		}
+		if value, err = openapi.Coerce(s, path, value); err != nil {
+			return err
+		}
+		// The schema decides whether strings are YAML, do not guess.
+		tryUnmarshal = false
+	}
+	scope.Info("applying", "path", path.String(), "value", value)
+	var inc *tpath.PathContext
+	if o.strictPaths {
+		inc, _, err = tpath.GetLeafPathContext(bo, path)
+	} else {
+		inc, _, err = tpath.GetPathContext(bo, path, true)
+	}
+	if err != nil {
+		return err
+	}
+	if p.Type == types.ValueTypeNull {
+		return tpath.WriteNullPathContext(inc)
+	}
+	return tpath.WritePathContext(inc, value, false, tryUnmarshal)
+}
+
+// patchValue returns the value to write for p, and whether string values may be unmarshaled into YAML maps.
+// from is the value of the valueFrom source of p, if it has one. Values with an explicit type or copied from another
+// object are never unmarshaled.
+func patchValue(p *types.K8sObjectOverlayPatch, from any) (any, bool, error) {
+	if p.ValueFrom != nil {
+		return copyValue(from), false, nil
+	}
+	if p.Type != "" {
+		v, err := typedValue(p.Type, p.Value)
+		return v, false, err
+	}
+	if p.Verbatim != "" && p.Value == "" {
+		return p.Verbatim, false, nil
+	}
+	v, err := util.UnmarshalValue(p.Value)
+	if err != nil {
+		return nil, false, err
+	}
+	return v, true, nil
+}
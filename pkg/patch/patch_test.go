@@ -15,11 +15,20 @@
 package patch
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"github.com/stackrox/k8s-overlay-patch/pkg/openapi"
+	"github.com/stackrox/k8s-overlay-patch/pkg/tpath"
+	"github.com/stackrox/k8s-overlay-patch/pkg/transform"
 	"github.com/stackrox/k8s-overlay-patch/pkg/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8syaml "sigs.k8s.io/yaml"
+	"strings"
 	"testing"
 
 	"github.com/stackrox/k8s-overlay-patch/pkg/util"
@@ -41,6 +50,43 @@ func TestYAMLManifestPatchValidation(t *testing.T) {
 		},
 	})
 	assert.ErrorContains(t, err, "value and verbatim cannot be used together in overlay 0 patch 0")
+
+	_, err = YAMLManifestPatch("", "", []*types.K8sObjectOverlay{
+		{
+			Patches: []*types.K8sObjectOverlayPatch{
+				{
+					Path: "spec.ports.[name~=grpc(].name",
+				},
+			},
+		},
+	})
+	assert.ErrorContains(t, err, `overlay 0 patch 0: path spec.ports.[name~=grpc(].name: invalid regular expression "grpc("`)
+
+	_, err = YAMLManifestPatch("", "", []*types.K8sObjectOverlay{
+		{
+			Patches: []*types.K8sObjectOverlayPatch{
+				{
+					Path: `metadata.annotations["a.b/c]`,
+				},
+			},
+		},
+	})
+	assert.ErrorContains(t, err, `overlay 0 patch 0: path metadata.annotations["a.b/c]: column 22: missing closing '"'`)
+
+	_, err = YAMLManifestPatch("", "", []*types.K8sObjectOverlay{
+		{
+			Patches: []*types.K8sObjectOverlayPatch{
+				{Path: "a", Type: "integer", Value: "1"},
+				{Path: "a", Type: "int", Value: "one"},
+				{Path: "a", Type: "null", Value: "x"},
+				{Path: "a", Type: "string", Verbatim: "x"},
+			},
+		},
+	})
+	assert.ErrorContains(t, err, `overlay 0 patch 0: unknown value type "integer", must be one of string, int, bool, float, yaml, json, base64 or null`)
+	assert.ErrorContains(t, err, `overlay 0 patch 1: cannot convert "one" to int`)
+	assert.ErrorContains(t, err, `overlay 0 patch 2: value must be empty for type null`)
+	assert.ErrorContains(t, err, `type and verbatim cannot be used together in overlay 0 patch 3`)
 }
 
 func TestPatchYAMLManifestSuccess(t *testing.T) {
@@ -576,29 +622,1457 @@ data:
 	}
 }
 
-func makeOverlayHeader(path, value string) string {
-	const (
-		patchCommon = `overlays:
+func TestPatchYAMLManifestCondition(t *testing.T) {
+	base := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: ns
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: app
+        image: registry.example.com/app:1.0
+        resources:
+          limits:
+            cpu: 100m
+      - name: sidecar
+        image: docker.io/sidecar:1.0
+`
+	tests := []struct {
+		desc    string
+		patches string
+		want    string
+		wantErr string
+	}{
+		{
+			desc: "exists false applies to missing node",
+			patches: `
+  - path: spec.template.spec.containers.[name:app].resources.limits.memory
+    value: 1Gi
+    when:
+      exists: false
+  - path: spec.template.spec.containers.[name:app].resources.limits.cpu
+    value: 200m
+    when:
+      exists: false`,
+			want: `
+      containers:
+      - name: app
+        image: registry.example.com/app:1.0
+        resources:
+          limits:
+            cpu: 100m
+            memory: 1Gi
+      - name: sidecar
+        image: docker.io/sidecar:1.0
+`,
+		},
+		{
+			desc: "matches on other path",
+			patches: `
+  - path: spec.template.spec.containers.[name:app].imagePullPolicy
+    value: Always
+    when:
+      path: spec.template.spec.containers.[name:app].image
+      matches: ^registry\.example\.com/
+  - path: spec.template.spec.containers.[name:sidecar].imagePullPolicy
+    value: Always
+    when:
+      path: spec.template.spec.containers.[name:sidecar].image
+      matches: ^registry\.example\.com/`,
+			want: `
+      containers:
+      - name: app
+        image: registry.example.com/app:1.0
+        imagePullPolicy: Always
+        resources:
+          limits:
+            cpu: 100m
+      - name: sidecar
+        image: docker.io/sidecar:1.0
+`,
+		},
+		{
+			desc: "equals",
+			patches: `
+  - path: spec.template.spec.containers.[name:app].resources.limits.cpu
+    value: 200m
+    when:
+      equals: 100m
+  - path: spec.template.spec.containers.[name:sidecar].image
+    value: docker.io/sidecar:2.0
+    when:
+      equals: docker.io/sidecar:1.1`,
+			want: `
+      containers:
+      - name: app
+        image: registry.example.com/app:1.0
+        resources:
+          limits:
+            cpu: 200m
+      - name: sidecar
+        image: docker.io/sidecar:1.0
+`,
+		},
+		{
+			desc: "expression",
+			patches: `
+  - path: spec.template.spec.containers.[name:app].image
+    value: registry.example.com/app:2.0
+    when:
+      expression: self.spec.replicas > 1 && self.metadata.name == 'app'
+  - path: spec.template.spec.containers.[name:sidecar].image
+    value: docker.io/sidecar:2.0
+    when:
+      expression: self.spec.replicas > 3`,
+			want: `
+      containers:
+      - name: app
+        image: registry.example.com/app:2.0
+        resources:
+          limits:
+            cpu: 100m
+      - name: sidecar
+        image: docker.io/sidecar:1.0
+`,
+		},
+		{
+			desc: "invalid regex",
+			patches: `
+  - path: spec.replicas
+    value: 1
+    when:
+      matches: "["`,
+			wantErr: "overlay 0 patch 0: invalid matches expression",
+		},
+		{
+			desc: "invalid expression",
+			patches: `
+  - path: spec.replicas
+    value: 1
+    when:
+      expression: self.spec.replicas +`,
+			wantErr: "overlay 0 patch 0: invalid expression",
+		},
+		{
+			desc: "non bool expression",
+			patches: `
+  - path: spec.replicas
+    value: 1
+    when:
+      expression: self.spec.replicas + 1`,
+			wantErr: "must evaluate to bool",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			rc := &KubernetesResourcesSpec{}
+			overlays := `overlays:
 - kind: Deployment
-  name: istio-citadel
+  name: app
+  patches:` + tt.patches
+			require.NoError(t, yaml.Unmarshal([]byte(overlays), rc))
+			got, err := YAMLManifestPatch(base, "ns", rc.Overlays)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			want := base[:strings.Index(base, "      containers:")] + strings.TrimPrefix(tt.want, "\n")
+			if !util.IsYAMLEqual(got, want) {
+				t.Errorf("YAMLManifestPatch(%s): got:\n%s\n\nwant:\n%s\nDiff:\n%s\n", tt.desc, got, want, util.YAMLDiff(got, want))
+			}
+		})
+	}
+}
+
+func TestConditionCache(t *testing.T) {
+	c := newConditionCache()
+	prg, err := c.program("self.spec.replicas > 1")
+	require.NoError(t, err)
+	again, err := c.program("self.spec.replicas > 1")
+	require.NoError(t, err)
+	assert.True(t, prg == again, "compiled again")
+
+	re, err := c.regex("^v[0-9]+$")
+	require.NoError(t, err)
+	reAgain, err := c.regex("^v[0-9]+$")
+	require.NoError(t, err)
+	assert.Same(t, re, reAgain)
+
+	for i := 0; i < 2; i++ {
+		_, err = c.program("self.spec.replicas +")
+		assert.Error(t, err)
+		_, err = c.regex("v[")
+		assert.ErrorContains(t, err, `invalid matches expression "v["`)
+	}
+}
+
+func TestConditionCostLimit(t *testing.T) {
+	items := make([]any, 200)
+	for i := range items {
+		items[i] = i
+	}
+	expr := "self.items.all(x, self.items.all(y, self.items.all(z, x + y + z >= 0)))"
+	prg, err := compileCEL(expr)
+	require.NoError(t, err)
+	_, err = evalCEL(map[any]any{"items": items}, expr, prg)
+	assert.ErrorContains(t, err, "cost limit exceeded")
+}
+
+func TestPatchYAMLManifestWildcard(t *testing.T) {
+	base := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: ns
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init1
+        env:
+        - name: A
+          value: a
+      - name: init2
+        env:
+        - name: B
+          value: b
+      containers:
+      - name: app
+        resources:
+          limits:
+            memory: 1Gi
+          requests:
+            memory: 1Gi
+      - name: sidecar
+`
+	overlays := `overlays:
+- kind: Deployment
+  name: app
   patches:
-  - path: `
-		valueStr = `    value: `
-	)
+  - path: spec.template.spec.containers.[*].imagePullPolicy
+    value: Always
+  - path: spec.template.spec.containers.[*].securityContext
+    value: |
+      runAsNonRoot: true
+  # Values written through a wildcard must not be shared between the selected nodes.
+  - path: spec.template.spec.containers.[name:sidecar].securityContext.runAsUser
+    value: 1000
+  - path: spec.template.spec.containers.[name:app].resources.*.memory
+    value: 2Gi
+  - path: spec.template.spec.initContainers.[*].env
+  - path: spec.template.spec.volumes.[*].name
+    value: unused
+`
+	want := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: ns
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init1
+      - name: init2
+      containers:
+      - name: app
+        imagePullPolicy: Always
+        securityContext:
+          runAsNonRoot: true
+        resources:
+          limits:
+            memory: 2Gi
+          requests:
+            memory: 2Gi
+      - name: sidecar
+        imagePullPolicy: Always
+        securityContext:
+          runAsNonRoot: true
+          runAsUser: 1000
+`
+	rc := &KubernetesResourcesSpec{}
+	require.NoError(t, yaml.Unmarshal([]byte(overlays), rc))
+	got, err := YAMLManifestPatch(base, "ns", rc.Overlays)
+	require.NoError(t, err)
+	if !util.IsYAMLEqual(got, want) {
+		t.Errorf("YAMLManifestPatch(): got:\n%s\n\nwant:\n%s\nDiff:\n%s\n", got, want, util.YAMLDiff(got, want))
+	}
+}
 
-	ret := patchCommon
-	ret += fmt.Sprintf("%s\n", path)
-	if value != "" {
-		ret += fmt.Sprintf("%s%s\n", valueStr, value)
+func TestPatchYAMLManifestWildcardMissingPaths(t *testing.T) {
+	base := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: ns
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          limits:
+            memory: 1Gi
+      - name: sidecar
+`
+	overlays := []*types.K8sObjectOverlay{
+		{
+			Kind: "Deployment",
+			Name: "app",
+			Patches: []*types.K8sObjectOverlayPatch{
+				{
+					Path:  "spec.template.spec.containers.[*].resources.limits.cpu",
+					Value: "1",
+				},
+			},
+		},
+	}
+
+	_, err := YAMLManifestPatch(base, "ns", overlays, WithStrictPaths(true))
+	assert.ErrorContains(t, err, "path not found at element resources")
+
+	got, err := YAMLManifestPatch(base, "ns", overlays, WithStrictPaths(false))
+	require.NoError(t, err)
+	want := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: ns
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          limits:
+            cpu: 1
+            memory: 1Gi
+      - name: sidecar
+        resources:
+          limits:
+            cpu: 1
+`
+	if !util.IsYAMLEqual(got, want) {
+		t.Errorf("YAMLManifestPatch(): got:\n%s\n\nwant:\n%s\nDiff:\n%s\n", got, want, util.YAMLDiff(got, want))
 	}
-	return ret
 }
 
-// errToString returns the string representation of err and the empty string if
-// err is nil.
-func errToString(err error) string {
-	if err == nil {
-		return ""
+func TestPatchYAMLManifestWildcardKeyNamedWildcard(t *testing.T) {
+	base := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm
+  namespace: ns
+data:
+  "*": x
+  a: x
+`
+	overlays := `overlays:
+- kind: ConfigMap
+  name: cm
+  patches:
+  - path: data.*
+    value: y
+`
+	want := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm
+  namespace: ns
+data:
+  "*": y
+  a: y
+`
+	rc := &KubernetesResourcesSpec{}
+	require.NoError(t, yaml.Unmarshal([]byte(overlays), rc))
+	got, err := YAMLManifestPatch(base, "ns", rc.Overlays)
+	require.NoError(t, err)
+	if !util.IsYAMLEqual(got, want) {
+		t.Errorf("YAMLManifestPatch(): got:\n%s\n\nwant:\n%s\nDiff:\n%s\n", got, want, util.YAMLDiff(got, want))
 	}
-	return err.Error()
+}
+
+func TestPatchYAMLManifestSelectors(t *testing.T) {
+	base := `
+apiVersion: v1
+kind: Service
+metadata:
+  name: svc
+  namespace: ns
+spec:
+  ports:
+  - name: grpc
+    protocol: UDP
+    port: 8080
+  - name: grpc
+    protocol: TCP
+    port: 8080
+`
+	overlays := `overlays:
+- kind: Service
+  name: svc
+  patches:
+  - path: spec.ports.[name:grpc,protocol:TCP].name
+    value: grpc-tcp
+  - path: spec.ports.[name:grpc,port:8080].appProtocol
+    value: grpc
+  - path: spec.ports.[name^=grpc-,protocol~=TCP|SCTP].port
+    value: 9090
+`
+	want := `
+apiVersion: v1
+kind: Service
+metadata:
+  name: svc
+  namespace: ns
+spec:
+  ports:
+  - name: grpc
+    protocol: UDP
+    port: 8080
+    appProtocol: grpc
+  - name: grpc-tcp
+    protocol: TCP
+    port: 9090
+`
+	rc := &KubernetesResourcesSpec{}
+	require.NoError(t, yaml.Unmarshal([]byte(overlays), rc))
+	got, err := YAMLManifestPatch(base, "ns", rc.Overlays)
+	require.NoError(t, err)
+	if !util.IsYAMLEqual(got, want) {
+		t.Errorf("YAMLManifestPatch(): got:\n%s\n\nwant:\n%s\nDiff:\n%s\n", got, want, util.YAMLDiff(got, want))
+	}
+}
+
+func TestPatchYAMLManifestQuotedKeys(t *testing.T) {
+	base := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm
+  namespace: ns
+  annotations:
+    app.kubernetes.io/name: old
+data:
+  a..b: x
+  "*": x
+  other: x
+`
+	overlays := `overlays:
+- kind: ConfigMap
+  name: cm
+  patches:
+  - path: metadata.annotations["app.kubernetes.io/name"]
+    value: new
+  - path: metadata.annotations["checksum.example.com//config"]
+    value: abc123
+  - path: data["a..b"]
+    value: y
+  - path: data["../etc"]
+    value: z
+  - path: data["*"]
+    value: star
+  - path: data["[0]"]
+    value: index
+`
+	want := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm
+  namespace: ns
+  annotations:
+    app.kubernetes.io/name: new
+    checksum.example.com//config: abc123
+data:
+  a..b: y
+  ../etc: z
+  "*": star
+  other: x
+  "[0]": index
+`
+	rc := &KubernetesResourcesSpec{}
+	require.NoError(t, yaml.Unmarshal([]byte(overlays), rc))
+	got, err := YAMLManifestPatch(base, "ns", rc.Overlays)
+	require.NoError(t, err)
+	if !util.IsYAMLEqual(got, want) {
+		t.Errorf("YAMLManifestPatch(): got:\n%s\n\nwant:\n%s\nDiff:\n%s\n", got, want, util.YAMLDiff(got, want))
+	}
+}
+
+func TestPatchYAMLManifestJSONPath(t *testing.T) {
+	base := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: d
+  namespace: ns
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1
+        args:
+        - --v=1
+      - name: sidecar
+        image: sidecar:1
+`
+	overlays := `overlays:
+- kind: Deployment
+  name: d
+  patches:
+  - path: spec.template.spec.containers[?(@.name=="app")].image
+    pathSyntax: jsonpath
+    value: app:2
+  - path: $.spec.template.spec.containers[?(@.name=="app")].args[?(@=="--v=1")]
+    value: --v=2
+  - path: '{.spec.template.spec.containers[*].imagePullPolicy}'
+    value: Always
+  - path: $.spec.template.spec.containers[?(@.name=="sidecar")].image
+    value: sidecar:2
+    when:
+      path: $.spec.template.spec.containers[?(@.name=="sidecar")].image
+      equals: sidecar:1
+`
+	want := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: d
+  namespace: ns
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:2
+        imagePullPolicy: Always
+        args:
+        - --v=2
+      - name: sidecar
+        image: sidecar:2
+        imagePullPolicy: Always
+`
+	rc := &KubernetesResourcesSpec{}
+	require.NoError(t, k8syaml.Unmarshal([]byte(overlays), rc))
+	got, err := YAMLManifestPatch(base, "ns", rc.Overlays)
+	require.NoError(t, err)
+	if !util.IsYAMLEqual(got, want) {
+		t.Errorf("YAMLManifestPatch(): got:\n%s\n\nwant:\n%s\nDiff:\n%s\n", got, want, util.YAMLDiff(got, want))
+	}
+}
+
+func TestPatchYAMLManifestStrictPaths(t *testing.T) {
+	base := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: d
+  namespace: ns
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+`
+	overlays := []*types.K8sObjectOverlay{
+		{
+			Kind: "Deployment",
+			Name: "d",
+			Patches: []*types.K8sObjectOverlayPatch{
+				{
+					Path:  "spec.tempalte.spec.nodeSelector",
+					Value: "disk: ssd",
+				},
+			},
+		},
+	}
+
+	_, err := YAMLManifestPatch(base, "ns", overlays, WithStrictPaths(true))
+	assert.EqualError(t, err, "path not found at element tempalte in path spec.tempalte.spec.nodeSelector, did you mean `template`?")
+
+	// Paths are lenient by default.
+	got, err := YAMLManifestPatch(base, "ns", overlays)
+	require.NoError(t, err)
+	want := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: d
+  namespace: ns
+spec:
+  tempalte:
+    spec:
+      nodeSelector:
+        disk: ssd
+  template:
+    spec:
+      containers:
+      - name: app
+`
+	if !util.IsYAMLEqual(got, want) {
+		t.Errorf("YAMLManifestPatch(): got:\n%s\n\nwant:\n%s\nDiff:\n%s\n", got, want, util.YAMLDiff(got, want))
+	}
+}
+
+func TestPatchYAMLManifestSchemaTypes(t *testing.T) {
+	base := `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    plural: widgets
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              size:
+                type: integer
+              color:
+                type: string
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: w
+  namespace: ns
+spec:
+  size: 1
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: d
+  namespace: ns
+  annotations: {}
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+`
+	patch := func(kind, path, value string) *types.K8sObjectOverlay {
+		return &types.K8sObjectOverlay{
+			Kind:    kind,
+			Name:    map[string]string{"Widget": "w", "Deployment": "d"}[kind],
+			Patches: []*types.K8sObjectOverlayPatch{{Path: path, Value: value}},
+		}
+	}
+	tests := []struct {
+		desc    string
+		overlay *types.K8sObjectOverlay
+		path    string
+		want    any
+		wantErr string
+	}{
+		{
+			desc:    "number annotation stays a string",
+			overlay: patch("Deployment", "metadata.annotations.port", "8080"),
+			path:    "metadata.annotations.port",
+			want:    "8080",
+		},
+		{
+			desc:    "YAML looking annotation stays a string",
+			overlay: patch("Deployment", "metadata.annotations.note", "note: keep"),
+			path:    "metadata.annotations.note",
+			want:    "note: keep",
+		},
+		{
+			desc:    "quoted replicas become an integer",
+			overlay: patch("Deployment", "spec.replicas", `"3"`),
+			path:    "spec.replicas",
+			want:    int64(3),
+		},
+		{
+			desc:    "boolean env value becomes a string",
+			overlay: patch("Deployment", "spec.template.spec.containers.[name:app].env", "- name: DEBUG\n  value: true"),
+			path:    "spec.template.spec.containers.[name:app].env.[name:DEBUG].value",
+			want:    "true",
+		},
+		{
+			desc:    "custom resource field from manifest CRD",
+			overlay: patch("Widget", "spec.color", "123"),
+			path:    "spec.color",
+			want:    "123",
+		},
+		{
+			desc:    "impossible conversion",
+			overlay: patch("Widget", "spec.size", "large"),
+			wantErr: `path spec.size: cannot convert "large" to integer`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := YAMLManifestPatch(base, "ns", []*types.K8sObjectOverlay{tt.overlay}, WithSchemaProvider(openapi.Builtin()))
+			if gotErr := errToString(err); gotErr != tt.wantErr {
+				t.Fatalf("YAMLManifestPatch(): gotErr:%s, wantErr:%s", gotErr, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			objs, err := object.ParseK8sObjectsFromYAMLManifest(got)
+			require.NoError(t, err)
+			for _, obj := range objs {
+				if obj.Kind != tt.overlay.Kind {
+					continue
+				}
+				pc, found, err := tpath.FindPathContext(obj.Unstructured(), mustPath(t, tt.path))
+				require.NoError(t, err)
+				require.True(t, found)
+				assert.Equal(t, tt.want, pc.Node)
+			}
+		})
+	}
+}
+
+func TestPatchYAMLManifestValueTypes(t *testing.T) {
+	base := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm
+  namespace: ns
+  annotations:
+    old: value
+data:
+  list: x
+spec: {}
+`
+	overlays := []*types.K8sObjectOverlay{
+		{
+			Kind: "ConfigMap",
+			Name: "cm",
+			Patches: []*types.K8sObjectOverlayPatch{
+				{Path: "metadata.annotations.note", Value: "note: keep", Type: types.ValueTypeString},
+				{Path: "metadata.annotations.empty", Value: "", Type: types.ValueTypeString},
+				{Path: "metadata.annotations.old", Type: types.ValueTypeNull},
+				{Path: "metadata.labels", Value: "app: web\ntier: \"1\"", Type: types.ValueTypeYAML},
+				{Path: "spec.replicas", Value: " 3 ", Type: types.ValueTypeInt},
+				{Path: "spec.enabled", Value: "true", Type: types.ValueTypeBool},
+				{Path: "spec.ratio", Value: "0.5", Type: types.ValueTypeFloat},
+				{Path: "spec.config", Value: `{"a": [1, "b"]}`, Type: types.ValueTypeJSON},
+				{Path: "data.secret", Value: "hunter2", Type: types.ValueTypeBase64},
+				{Path: "data.list", Value: "[a, b]", Type: types.ValueTypeString},
+			},
+		},
+	}
+	got, err := YAMLManifestPatch(base, "ns", overlays)
+	require.NoError(t, err)
+	want := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm
+  namespace: ns
+  annotations:
+    empty: ""
+    note: "note: keep"
+    old: null
+  labels:
+    app: web
+    tier: "1"
+data:
+  list: "[a, b]"
+  secret: aHVudGVyMg==
+spec:
+  config:
+    a:
+    - 1
+    - b
+  enabled: true
+  ratio: 0.5
+  replicas: 3
+`
+	if !util.IsYAMLEqual(got, want) {
+		t.Errorf("YAMLManifestPatch(): got:\n%s\n\nwant:\n%s\nDiff:\n%s\n", got, want, util.YAMLDiff(got, want))
+	}
+}
+
+func TestPatchYAMLManifestLosslessNumbers(t *testing.T) {
+	base := `
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: w
+  namespace: ns
+spec:
+  template: {}
+`
+	overlays := []*types.K8sObjectOverlay{
+		{
+			Kind: "Widget",
+			Name: "w",
+			Patches: []*types.K8sObjectOverlayPatch{
+				{Path: "spec.runAsUser", Value: "9007199254740993"},
+				{Path: "spec.maxBytes", Value: "18446744073709551615"},
+				{Path: "spec.version", Value: "1.10"},
+				{Path: "spec.replicas", Value: "3"},
+				{Path: "spec.template", Value: "limits:\n  bytes: 10737418241\n  ratio: 0.25"},
+				{Path: "spec.json", Value: `{"id": 9007199254740995}`, Type: types.ValueTypeJSON},
+			},
+		},
+	}
+	got, err := YAMLManifestPatch(base, "ns", overlays)
+	require.NoError(t, err)
+	for _, want := range []string{
+		"runAsUser: 9007199254740993\n",
+		"maxBytes: 18446744073709551615\n",
+		"version: 1.10\n",
+		"replicas: 3\n",
+		"bytes: 10737418241\n",
+		"ratio: 0.25\n",
+		"id: 9007199254740995\n",
+	} {
+		assert.Contains(t, got, want)
+	}
+}
+
+func makeOverlayHeader(path, value string) string {
+	const (
+		patchCommon = `overlays:
+- kind: Deployment
+  name: istio-citadel
+  patches:
+  - path: `
+		valueStr = `    value: `
+	)
+
+	ret := patchCommon
+	ret += fmt.Sprintf("%s\n", path)
+	if value != "" {
+		ret += fmt.Sprintf("%s%s\n", valueStr, value)
+	}
+	return ret
+}
+
+// errToString returns the string representation of err and the empty string if
+// err is nil.
+func errToString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func TestPatchYAMLManifestTemplates(t *testing.T) {
+	base := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  template:
+    metadata:
+      annotations: {}
+    spec:
+      containers:
+      - name: app
+        image: app:1
+`
+	overlays := []*types.K8sObjectOverlay{
+		{
+			Kind: "Deployment",
+			Name: "web",
+			Patches: []*types.K8sObjectOverlayPatch{
+				{Path: "metadata.annotations", Value: "{}"},
+				{Path: "metadata.annotations.owner", Value: "{{ .Object.metadata.name }}-{{ .Namespace }}", Template: true},
+				{Path: "metadata.annotations.team", Value: `{{ .Vars.team | default "none" }}`, Template: true},
+				{Path: "metadata.annotations.tier", Value: `{{ .Object.metadata.labels.tier | default "backend" | quote }}`, Template: true},
+				{Path: "metadata.annotations.token", Value: "{{ .Vars.token | b64enc }}", Template: true},
+				{Path: "metadata.annotations.hash", Value: "{{ .Object.spec.template.spec.containers | toYaml | sha256 }}", Template: true},
+				{Path: "metadata.annotations.replicas", Value: "{{ .Vars.replicas }}", Type: types.ValueTypeString, Template: true},
+				{Path: "metadata.annotations.missing", Value: "[{{ .Vars.missing }}]", Type: types.ValueTypeString, Template: true},
+				{Path: "metadata.annotations.literal", Value: "{{ .Vars.literal }}", Type: types.ValueTypeString, Template: true},
+				{Path: "metadata.annotations.loop", Value: `{{ range $k, $v := .Object.metadata.labels }}{{ $k }}={{ $v }}{{ end }}`, Type: types.ValueTypeString, Template: true},
+				{Path: "spec.template.metadata.annotations.labels", Verbatim: "{{ toYaml .Object.metadata.labels }}", Template: true},
+				{Path: "spec.template.metadata.annotations.literal", Verbatim: "{{ .Vars.team }}"},
+				{Path: "spec.replicas", Value: "{{ .Vars.replicas }}", Template: true},
+			},
+		},
+	}
+	vars := map[string]any{"token": "secret", "replicas": 3, "literal": "<no value>"}
+	got, err := YAMLManifestPatch(base, "ns", overlays, WithVars(vars))
+	require.NoError(t, err)
+	want := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+  annotations:
+    owner: web-ns
+    team: none
+    tier: backend
+    token: c2VjcmV0
+    hash: 412cdd8830fcb76afcc009b6b4e8c4b163d69931458218b6e9da53ae2bb34c84
+    replicas: "3"
+    missing: "[]"
+    literal: "<no value>"
+    loop: app=web
+spec:
+  replicas: 3
+  template:
+    metadata:
+      annotations:
+        labels: "app: web"
+        literal: "{{ .Vars.team }}"
+    spec:
+      containers:
+      - name: app
+        image: app:1
+`
+	if !util.IsYAMLEqual(got, want) {
+		t.Errorf("YAMLManifestPatch(): got:\n%s\n\nwant:\n%s\nDiff:\n%s\n", got, want, util.YAMLDiff(got, want))
+	}
+}
+
+func TestPatchYAMLManifestTemplateErrors(t *testing.T) {
+	base := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm
+data: {}
+`
+	_, err := YAMLManifestPatch(base, "ns", []*types.K8sObjectOverlay{
+		{
+			Kind:    "ConfigMap",
+			Name:    "cm",
+			Patches: []*types.K8sObjectOverlayPatch{{Path: "data.a", Value: "{{ .Vars.x", Template: true}},
+		},
+	})
+	assert.ErrorContains(t, err, `overlay 0 patch 0: template: value:1: unclosed action`)
+
+	_, err = YAMLManifestPatch(base, "ns", []*types.K8sObjectOverlay{
+		{
+			Kind:    "ConfigMap",
+			Name:    "cm",
+			Patches: []*types.K8sObjectOverlayPatch{{Path: "data.a", Value: "{{ b64enc }}", Template: true}},
+		},
+	})
+	assert.ErrorContains(t, err, `path data.a: template: value:1:3: executing "value" at <b64enc>: wrong number of args for b64enc: want 1 got 0`)
+}
+
+func TestPatchYAMLManifestValueFrom(t *testing.T) {
+	base := `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - name: http
+    port: 8080
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: web-tls
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        env:
+        - name: PORT
+          value: "80"
+      volumes:
+      - name: tls
+        secret:
+          secretName: tls
+`
+	overlays := []*types.K8sObjectOverlay{
+		{
+			Kind: "Deployment",
+			Name: "web",
+			Patches: []*types.K8sObjectOverlayPatch{
+				{
+					Path:      "spec.template.spec.containers.[name:app].env.[name:PORT].value",
+					ValueFrom: &types.K8sObjectOverlayPatchSource{Kind: "Service", Name: "web", Path: "spec.ports.[name:http].port"},
+				},
+				{
+					Path:      "spec.template.spec.volumes.[name:tls].secret.secretName",
+					ValueFrom: &types.K8sObjectOverlayPatchSource{Kind: "Secret", Name: "web-tls", Path: "metadata.name"},
+				},
+				{
+					Path:      "spec.template.spec.containers.[name:app].ports",
+					ValueFrom: &types.K8sObjectOverlayPatchSource{Kind: "Service", Name: "web", Path: "spec.ports"},
+				},
+			},
+		},
+		{
+			// The Service is patched before its port is copied, even though it comes first.
+			Kind:    "Service",
+			Name:    "web",
+			Patches: []*types.K8sObjectOverlayPatch{{Path: "spec.ports.[name:http].port", Value: "9090"}},
+		},
+	}
+	got, err := YAMLManifestPatch(base, "ns", overlays, WithSchemaProvider(openapi.Builtin()))
+	require.NoError(t, err)
+	want := `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - name: http
+    port: 9090
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: web-tls
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        env:
+        - name: PORT
+          value: "9090"
+        ports:
+        - name: http
+          port: 9090
+      volumes:
+      - name: tls
+        secret:
+          secretName: web-tls
+`
+	if !util.IsYAMLEqual(got, want) {
+		t.Errorf("YAMLManifestPatch(): got:\n%s\n\nwant:\n%s\nDiff:\n%s\n", got, want, util.YAMLDiff(got, want))
+	}
+}
+
+func TestPatchYAMLManifestValueFromSelf(t *testing.T) {
+	base := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+data:
+  key: a
+`
+	overlays := []*types.K8sObjectOverlay{{
+		Kind: "ConfigMap",
+		Name: "a",
+		Patches: []*types.K8sObjectOverlayPatch{
+			{Path: "data.key", Value: "patched"},
+			{Path: "data.copy", ValueFrom: &types.K8sObjectOverlayPatchSource{Kind: "ConfigMap", Name: "a", Path: "data.key"}},
+		},
+	}}
+	got, err := YAMLManifestPatch(base, "ns", overlays)
+	require.NoError(t, err)
+	// The value is copied from the object before its overlays are applied.
+	want := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+data:
+  key: patched
+  copy: a
+`
+	if !util.IsYAMLEqual(got, want) {
+		t.Errorf("YAMLManifestPatch(): got:\n%s\n\nwant:\n%s\nDiff:\n%s\n", got, want, util.YAMLDiff(got, want))
+	}
+}
+
+func TestPatchYAMLManifestValueFromErrors(t *testing.T) {
+	base := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+data:
+  key: a
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: b
+data:
+  key: b
+`
+	tests := []struct {
+		desc     string
+		overlays []*types.K8sObjectOverlay
+		wantErrs []string
+	}{
+		{
+			desc: "invalid",
+			overlays: []*types.K8sObjectOverlay{{
+				Kind: "ConfigMap",
+				Name: "a",
+				Patches: []*types.K8sObjectOverlayPatch{
+					{Path: "data.key", Value: "x", ValueFrom: &types.K8sObjectOverlayPatchSource{Kind: "ConfigMap", Name: "b", Path: "data.key"}},
+					{Path: "data.key", ValueFrom: &types.K8sObjectOverlayPatchSource{Kind: "ConfigMap", Path: "data.key"}},
+				},
+			}},
+			wantErrs: []string{
+				"valueFrom cannot be used together with value, verbatim or type in overlay 0 patch 0",
+				"overlay 0 patch 1: valueFrom must set kind, name and path",
+			},
+		},
+		{
+			desc: "missing source",
+			overlays: []*types.K8sObjectOverlay{{
+				Kind: "ConfigMap",
+				Name: "a",
+				Patches: []*types.K8sObjectOverlayPatch{
+					{Path: "data.key", ValueFrom: &types.K8sObjectOverlayPatchSource{Kind: "Secret", Name: "b", Path: "data.key"}},
+					{Path: "data.other", ValueFrom: &types.K8sObjectOverlayPatchSource{Kind: "ConfigMap", Name: "b", Path: "data.missing"}},
+				},
+			}},
+			wantErrs: []string{
+				"path data.key: valueFrom source Secret:b not found in manifest",
+				"path data.other: valueFrom path data.missing not found in ConfigMap::b",
+			},
+		},
+		{
+			desc: "cycle",
+			overlays: []*types.K8sObjectOverlay{
+				{
+					Kind:    "ConfigMap",
+					Name:    "a",
+					Patches: []*types.K8sObjectOverlayPatch{{Path: "data.key", ValueFrom: &types.K8sObjectOverlayPatchSource{Kind: "ConfigMap", Name: "b", Path: "data.key"}}},
+				},
+				{
+					Kind:    "ConfigMap",
+					Name:    "b",
+					Patches: []*types.K8sObjectOverlayPatch{{Path: "data.key", ValueFrom: &types.K8sObjectOverlayPatchSource{Kind: "ConfigMap", Name: "a", Path: "data.key"}}},
+				},
+			},
+			wantErrs: []string{
+				"path data.key: valueFrom cycle: ConfigMap::a -> ConfigMap::b -> ConfigMap::a",
+				"path data.key: valueFrom source ConfigMap::b could not be patched: path data.key: valueFrom cycle: ConfigMap::a -> ConfigMap::b -> ConfigMap::a",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			_, err := YAMLManifestPatch(base, "ns", tt.overlays)
+			for _, want := range tt.wantErrs {
+				assert.ErrorContains(t, err, want)
+			}
+		})
+	}
+}
+
+func TestPatchYAMLManifestTransformers(t *testing.T) {
+	base := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  key: value
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      volumes:
+      - name: config
+        configMap:
+          name: config
+`
+	overlays := []*types.K8sObjectOverlay{
+		{Kind: "ConfigMap", Name: "config", Patches: []*types.K8sObjectOverlayPatch{{Path: "data.key", Value: "patched"}}},
+	}
+	got, err := YAMLManifestPatch(base, "ns", overlays, WithTransformers(transform.ConfigChecksums("ns")))
+	require.NoError(t, err)
+	// The checksum is taken from the patched ConfigMap.
+	sum := sha256.Sum256([]byte(`{"data":{"key":"patched"}}`))
+	assert.Contains(t, got, "checksum/configmap-config: "+hex.EncodeToString(sum[:]))
+
+	_, err = YAMLManifestPatch(base, "ns", []*types.K8sObjectOverlay{{Kind: "ConfigMap", Name: "missing"}},
+		WithTransformers(transform.ConfigChecksums("ns")))
+	assert.ErrorContains(t, err, "overlay for ConfigMap:missing does not match any object")
+
+	// Objects are validated after the transformers are applied, including those no overlay matched.
+	configMap := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n"
+	_, err = YAMLManifestPatch(configMap, "ns", nil, WithValidation(openapi.Builtin()))
+	require.NoError(t, err)
+	_, err = YAMLManifestPatch(configMap, "ns", nil, WithValidation(openapi.Builtin()),
+		WithTransformers(transform.Rename("ns", "Invalid-", "")))
+	assert.ErrorContains(t, err, "patched object ConfigMap::Invalid-config is invalid")
+}
+
+func TestPatchYAMLManifestAdditions(t *testing.T) {
+	base := `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: ns
+spec:
+  selector:
+    app: web
+  ports:
+  - port: 80
+`
+	adds := []*types.K8sObjectAddition{
+		{
+			Object: map[string]any{
+				"apiVersion": "policy/v1",
+				"kind":       "PodDisruptionBudget",
+				"metadata":   map[string]any{"name": "web", "namespace": "ns"},
+				"spec":       map[string]any{"minAvailable": 1},
+			},
+		},
+		{
+			CloneFrom: &types.K8sObjectReference{Kind: "Service", Name: "web"},
+			Name:      "web-internal",
+			Patches:   []*types.K8sObjectOverlayPatch{{Path: "spec.ports.[port:80].port", Value: "8080"}},
+		},
+	}
+	overlays := []*types.K8sObjectOverlay{
+		{
+			Kind:    "Service",
+			Name:    "web-internal",
+			Patches: []*types.K8sObjectOverlayPatch{{Path: "spec.type", Value: "ClusterIP"}},
+		},
+		{
+			Kind:    "PodDisruptionBudget",
+			Name:    "web",
+			Patches: []*types.K8sObjectOverlayPatch{{Path: "spec.selector", Value: "matchLabels:\n  app: web"}},
+		},
+	}
+	got, err := YAMLManifestPatch(base, "ns", overlays, WithAdditions(adds))
+	require.NoError(t, err)
+	want := `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: ns
+spec:
+  selector:
+    app: web
+  ports:
+  - port: 80
+---
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: web
+  namespace: ns
+spec:
+  minAvailable: 1
+  selector:
+    matchLabels:
+      app: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web-internal
+  namespace: ns
+spec:
+  selector:
+    app: web
+  ports:
+  - port: 8080
+  type: ClusterIP
+`
+	if !util.IsYAMLEqual(got, want) {
+		t.Errorf("YAMLManifestPatch(): got:\n%s\n\nwant:\n%s\nDiff:\n%s\n", got, want, util.YAMLDiff(got, want))
+	}
+}
+
+func TestPatchYAMLManifestAdditionErrors(t *testing.T) {
+	base := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm
+data: {}
+`
+	_, err := YAMLManifestPatch(base, "ns", nil, WithAdditions([]*types.K8sObjectAddition{
+		{},
+		{Object: map[string]any{"kind": "ConfigMap"}, CloneFrom: &types.K8sObjectReference{Kind: "ConfigMap", Name: "cm"}},
+		{CloneFrom: &types.K8sObjectReference{Kind: "ConfigMap", Name: "cm"}},
+		{Object: map[string]any{"apiVersion": "v1", "kind": "ConfigMap"}},
+		{CloneFrom: &types.K8sObjectReference{Kind: "Secret", Name: "cm"}, Name: "copy"},
+		{Object: map[string]any{"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]any{"name": "cm"}}},
+		{CloneFrom: &types.K8sObjectReference{Kind: "ConfigMap", Name: "cm"}, Name: "copy", Patches: []*types.K8sObjectOverlayPatch{{Path: "data.a", Value: "x", Verbatim: "y"}}},
+	}))
+	for _, want := range []string{
+		"add 0 must set object or cloneFrom",
+		"object and cloneFrom cannot be used together in add 1",
+		"add 2 must set the kind and name of cloneFrom and the name of the clone",
+		"add 3: object must set kind and metadata.name",
+		"add 4: cloneFrom Secret:cm not found in manifest",
+		"add 5: ConfigMap::cm already exists in manifest",
+		"value and verbatim cannot be used together in add 6 patch 0",
+	} {
+		assert.ErrorContains(t, err, want)
+	}
+	// Invalid additions are not added, so they have no other errors.
+	for _, unwanted := range []string{"add 0:", "add 1:", "add 2:", "add 6:"} {
+		assert.NotContains(t, err.Error(), unwanted)
+	}
+}
+
+func TestPatchYAMLManifestRemove(t *testing.T) {
+	base := `
+apiVersion: policy/v1beta1
+kind: PodSecurityPolicy
+metadata:
+  name: psp
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: ns
+  labels:
+    monitoring: "true"
+---
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+metadata:
+  name: web
+  namespace: ns
+  labels:
+    monitoring: "true"
+---
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+metadata:
+  name: db
+  namespace: ns
+  labels:
+    monitoring: "true"
+---
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+metadata:
+  name: other
+  namespace: other
+  labels:
+    monitoring: "true"
+`
+	overlays := []*types.K8sObjectOverlay{
+		{Kind: "PodSecurityPolicy", Name: "psp", Remove: true},
+		{
+			Kind:     "ServiceMonitor",
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "true"}},
+			Remove:   true,
+		},
+		{Kind: "Secret", Name: "missing", Remove: true, Optional: true},
+	}
+	var removed []string
+	got, err := YAMLManifestPatch(base, "ns", overlays, WithRemovalReporter(func(key string) {
+		removed = append(removed, key)
+	}))
+	require.NoError(t, err)
+	want := `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: ns
+  labels:
+    monitoring: "true"
+---
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+metadata:
+  name: other
+  namespace: other
+  labels:
+    monitoring: "true"
+`
+	if !util.IsYAMLEqual(got, want) {
+		t.Errorf("YAMLManifestPatch(): got:\n%s\n\nwant:\n%s\nDiff:\n%s\n", got, want, util.YAMLDiff(got, want))
+	}
+	assert.Equal(t, []string{"PodSecurityPolicy::psp", "ServiceMonitor:ns:web", "ServiceMonitor:ns:db"}, removed)
+}
+
+func TestPatchYAMLManifestRemoveErrors(t *testing.T) {
+	base := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm
+data: {}
+`
+	_, err := YAMLManifestPatch(base, "ns", []*types.K8sObjectOverlay{
+		{Kind: "ConfigMap", Name: "cm", Remove: true, Patches: []*types.K8sObjectOverlayPatch{{Path: "data.a", Value: "b"}}},
+		{Kind: "ConfigMap", Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"a": "b"}}},
+		{Kind: "ConfigMap", Remove: true, Selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "a", Operator: "Bogus"},
+		}}},
+		{Kind: "Secret", Remove: true, Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"a": "b"}}},
+		{Kind: "ConfigMap", Remove: true, Selector: &metav1.LabelSelector{}},
+	})
+	for _, want := range []string{
+		"remove and patches cannot be used together in overlay 0",
+		"selector can only be used together with remove in overlay 1",
+		`overlay 2: invalid selector: "Bogus" is not a valid label selector operator`,
+		"overlay with selector a=b for Secret does not match any object in output manifest",
+		"selector must not be empty in overlay 4",
+	} {
+		assert.ErrorContains(t, err, want)
+	}
+
+	// An empty patches list is not a patch, as in the CEL rule of K8sObjectOverlay.
+	got, err := YAMLManifestPatch(base, "ns", []*types.K8sObjectOverlay{
+		{Kind: "ConfigMap", Name: "cm", Remove: true, Patches: []*types.K8sObjectOverlayPatch{}},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, strings.TrimSpace(got))
+}
+
+// mustPath returns the path s, failing t if it does not parse.
+func mustPath(t *testing.T, s string) util.Path {
+	t.Helper()
+	p, err := util.ParsePath(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
 }
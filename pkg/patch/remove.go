@@ -0,0 +1,57 @@
+package patch
+
+import (
+	"fmt"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"github.com/stackrox/k8s-overlay-patch/pkg/types"
+	"github.com/stackrox/k8s-overlay-patch/pkg/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// validateRemoval reports whether the remove and selector settings of overlay are used together correctly.
+func validateRemoval(overlayIndex int, overlay *types.K8sObjectOverlay) error {
+	var errs util.Errors
+	if overlay.Remove && len(overlay.Patches) > 0 {
+		errs = util.AppendErr(errs, fmt.Errorf("remove and patches cannot be used together in overlay %d", overlayIndex))
+	}
+	if overlay.Selector != nil {
+		if !overlay.Remove {
+			errs = util.AppendErr(errs, fmt.Errorf("selector can only be used together with remove in overlay %d", overlayIndex))
+		}
+		// An empty selector selects every object, which is never what a removal means.
+		if len(overlay.Selector.MatchLabels) == 0 && len(overlay.Selector.MatchExpressions) == 0 {
+			errs = util.AppendErr(errs, fmt.Errorf("selector must not be empty in overlay %d", overlayIndex))
+		} else if _, err := metav1.LabelSelectorAsSelector(overlay.Selector); err != nil {
+			errs = util.AppendErr(errs, fmt.Errorf("overlay %d: invalid selector: %v", overlayIndex, err))
+		}
+	}
+	return errs.ToError()
+}
+
+// selectorMatches reports whether obj has the labels the selector of overlay selects, and the kind and name of overlay
+// if they are set, in either the default namespace or no namespace.
+func selectorMatches(overlay *types.K8sObjectOverlay, obj *object.K8sObject, defaultNamespace string) bool {
+	if obj.Namespace != defaultNamespace && obj.Namespace != "" {
+		return false
+	}
+	if (overlay.Kind != "" && overlay.Kind != obj.Kind) || (overlay.Name != "" && overlay.Name != obj.Name) {
+		return false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(overlay.Selector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(obj.UnstructuredObject().GetLabels()))
+}
+
+// unmatchedSelectorError returns the error for an overlay with a selector that does not match any object.
+func unmatchedSelectorError(overlay *types.K8sObjectOverlay) error {
+	kind := overlay.Kind
+	if kind == "" {
+		kind = "any kind"
+	}
+	return fmt.Errorf("overlay with selector %s for %s does not match any object in output manifest",
+		metav1.FormatLabelSelector(overlay.Selector), kind)
+}
@@ -0,0 +1,114 @@
+package patch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"github.com/stackrox/k8s-overlay-patch/pkg/tpath"
+	"github.com/stackrox/k8s-overlay-patch/pkg/types"
+	"github.com/stackrox/k8s-overlay-patch/pkg/util"
+)
+
+// sourceFunc returns the value src refers to, for a patch with the given path syntax.
+type sourceFunc func(syntax string, src *types.K8sObjectOverlayPatchSource) (any, error)
+
+// validateSource reports whether src identifies an object and has a valid path.
+func validateSource(syntax string, src *types.K8sObjectOverlayPatchSource) error {
+	if src.Kind == "" || src.Name == "" || src.Path == "" {
+		return fmt.Errorf("valueFrom must set kind, name and path")
+	}
+	return validatePath(syntax, src.Path)
+}
+
+// sourceValue returns the value src refers to, taken from the source object after its overlays are applied.
+func (m *manifestPatcher) sourceValue(syntax string, src *types.K8sObjectOverlayPatchSource) (any, error) {
+	candidates := m.find(src.Kind, src.Name)
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("valueFrom source %s:%s not found in manifest", src.Kind, src.Name)
+	case 1:
+	default:
+		return nil, fmt.Errorf("valueFrom source %s:%s matches multiple objects in manifest:\n%s",
+			src.Kind, src.Name, strings.Join(candidates.Keys(), "\n"))
+	}
+	obj := candidates[0]
+	y, err := m.sourceYAML(obj)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := util.UnmarshalValue(y)
+	if err != nil {
+		return nil, err
+	}
+	path, err := util.ParsePathSyntax(syntax, src.Path)
+	if err != nil {
+		return nil, err
+	}
+	nc, found, err := tpath.FindPathContext(tree, path)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("valueFrom path %s not found in %s", src.Path, obj.Hash())
+	}
+	node := nc.Node
+	if p, ok := node.(*any); ok {
+		node = *p
+	}
+	return node, nil
+}
+
+// sourceYAML returns the YAML of obj, the source of a copied value, after its overlays are applied. If obj is the object
+// being patched, which copies a value from itself, it returns obj before its overlays are applied.
+func (m *manifestPatcher) sourceYAML(obj *object.K8sObject) (string, error) {
+	if n := len(m.inProgress); n > 0 && m.inProgress[n-1] == obj {
+		y, err := obj.YAML()
+		return string(y), err
+	}
+	for i, o := range m.inProgress {
+		if o == obj {
+			var cycle []string
+			for _, c := range m.inProgress[i:] {
+				cycle = append(cycle, c.Hash())
+			}
+			return "", fmt.Errorf("valueFrom cycle: %s -> %s", strings.Join(cycle, " -> "), obj.Hash())
+		}
+	}
+	r := m.patch(obj)
+	if len(r.errs) > 0 {
+		return "", fmt.Errorf("valueFrom source %s could not be patched: %v", obj.Hash(), r.errs.ToError())
+	}
+	return r.yaml, nil
+}
+
+// find returns the objects with the given kind and name, in the default namespace or without a namespace.
+func (m *manifestPatcher) find(kind, name string) object.K8sObjects {
+	var out object.K8sObjects
+	for _, obj := range m.objs {
+		oh := obj.Hash()
+		if oh == object.Hash(kind, m.defaultNamespace, name) || oh == object.Hash(kind, "", name) {
+			out = append(out, obj)
+		}
+	}
+	return out
+}
+
+// copyValue returns a deep copy of the maps and lists in v, so that a value copied to several paths is not shared.
+func copyValue(v any) any {
+	switch vv := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(vv))
+		for k, e := range vv {
+			out[k] = copyValue(e)
+		}
+		return out
+	case []any:
+		out := make([]any, len(vv))
+		for i, e := range vv {
+			out[i] = copyValue(e)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,104 @@
+package patch
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"github.com/stackrox/k8s-overlay-patch/pkg/types"
+	"github.com/stackrox/k8s-overlay-patch/pkg/util"
+)
+
+// maxObjectSuggestions is the maximum number of objects suggested for an overlay that does not match any object.
+const maxObjectSuggestions = 5
+
+// objectSuggestion is an object that an unmatched overlay was possibly meant for.
+type objectSuggestion struct {
+	obj *object.K8sObject
+	// rank orders suggestions by how the object differs from the overlay, lower is better.
+	rank int
+	// distance orders suggestions of the same rank, lower is better.
+	distance int
+	// reasons explain how the object differs from the overlay.
+	reasons []string
+}
+
+// Ranks of suggested objects.
+const (
+	rankNamespace = iota
+	rankName
+	rankKind
+)
+
+// suggestObjects returns the objects that are most similar to the unmatched overlay, best first: objects of the same
+// kind and name in another namespace, objects of the same kind with a similar name and objects of another kind with
+// the same name. Objects whose apiVersion differs from the one of the overlay come after those of the same rank that
+// have it.
+func suggestObjects(overlay *types.K8sObjectOverlay, objs object.K8sObjects, defaultNamespace string) []objectSuggestion {
+	var out []objectSuggestion
+	for _, obj := range objs {
+		s := objectSuggestion{obj: obj}
+		switch {
+		case obj.Kind == overlay.Kind && obj.Name == overlay.Name:
+			s.rank = rankNamespace
+			s.reasons = []string{fmt.Sprintf("namespace is %q, not %q", obj.Namespace, defaultNamespace)}
+		case obj.Kind == overlay.Kind && similar(overlay.Name, obj.Name):
+			s.rank, s.distance = rankName, util.EditDistance(overlay.Name, obj.Name)
+			s.reasons = []string{"name differs"}
+		case obj.Name == overlay.Name:
+			s.rank, s.distance = rankKind, util.EditDistance(overlay.Kind, obj.Kind)
+			s.reasons = []string{"kind differs"}
+		default:
+			continue
+		}
+		if overlay.ApiVersion != "" && obj.Version() != overlay.ApiVersion {
+			s.distance++
+			s.reasons = append(s.reasons, fmt.Sprintf("apiVersion is %s, not %s", obj.Version(), overlay.ApiVersion))
+		}
+		out = append(out, s)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].rank != out[j].rank {
+			return out[i].rank < out[j].rank
+		}
+		return out[i].distance < out[j].distance
+	})
+	if len(out) > maxObjectSuggestions {
+		out = out[:maxObjectSuggestions]
+	}
+	return out
+}
+
+// similar reports whether a and b are likely to refer to the same thing: they differ only in case, one contains the
+// other, or their edit distance is small compared to the length of a.
+func similar(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	la, lb := strings.ToLower(a), strings.ToLower(b)
+	if strings.Contains(la, lb) || strings.Contains(lb, la) {
+		return true
+	}
+	return util.EditDistance(la, lb) <= max(2, len(la)/3)
+}
+
+// unmatchedOverlayError returns the error for an overlay that does not match any object in objs. It suggests similar
+// objects, and lists all objects if verbose is set.
+func unmatchedOverlayError(overlay *types.K8sObjectOverlay, objs object.K8sObjects, defaultNamespace string, verbose bool) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "overlay for %s:%s does not match any object in output manifest.", overlay.Kind, overlay.Name)
+	if suggestions := suggestObjects(overlay, objs, defaultNamespace); len(suggestions) > 0 {
+		sb.WriteString(" Did you mean:")
+		for _, s := range suggestions {
+			fmt.Fprintf(&sb, "\n  %s (%s)", s.obj.Hash(), strings.Join(s.reasons, ", "))
+		}
+	} else {
+		fmt.Fprintf(&sb, " No similar objects found among %d objects.", len(objs))
+	}
+	if verbose {
+		fmt.Fprintf(&sb, "\nAvailable objects are:\n%s", strings.Join(objs.Keys(), "\n"))
+	}
+	return errors.New(sb.String())
+}
@@ -0,0 +1,104 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"github.com/stackrox/k8s-overlay-patch/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmatchedOverlayError(t *testing.T) {
+	manifest := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: central
+  namespace: ns
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: central-db
+  namespace: ns
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: scanner
+  namespace: other
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: centrl
+  namespace: ns
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unrelated
+  namespace: ns
+`
+	objs, err := object.ParseK8sObjectsFromYAMLManifest(manifest)
+	require.NoError(t, err)
+
+	tests := []struct {
+		desc    string
+		overlay *types.K8sObjectOverlay
+		verbose bool
+		want    string
+	}{
+		{
+			desc:    "similar names",
+			overlay: &types.K8sObjectOverlay{Kind: "Deployment", Name: "centrl"},
+			want: `overlay for Deployment:centrl does not match any object in output manifest. Did you mean:
+  Deployment:ns:central (name differs)
+  Service:ns:centrl (kind differs)`,
+		},
+		{
+			desc:    "namespace",
+			overlay: &types.K8sObjectOverlay{Kind: "Deployment", Name: "scanner"},
+			want: `overlay for Deployment:scanner does not match any object in output manifest. Did you mean:
+  Deployment:other:scanner (namespace is "other", not "ns")`,
+		},
+		{
+			desc:    "apiVersion",
+			overlay: &types.K8sObjectOverlay{ApiVersion: "apps/v1", Kind: "Deployment", Name: "centrl"},
+			want: `overlay for Deployment:centrl does not match any object in output manifest. Did you mean:
+  Deployment:ns:central (name differs)
+  Service:ns:centrl (kind differs, apiVersion is v1, not apps/v1)`,
+		},
+		{
+			desc:    "contained name",
+			overlay: &types.K8sObjectOverlay{Kind: "Deployment", Name: "db"},
+			want: `overlay for Deployment:db does not match any object in output manifest. Did you mean:
+  Deployment:ns:central-db (name differs)`,
+		},
+		{
+			desc:    "nothing similar",
+			overlay: &types.K8sObjectOverlay{Kind: "Secret", Name: "tls"},
+			want:    `overlay for Secret:tls does not match any object in output manifest. No similar objects found among 5 objects.`,
+		},
+		{
+			desc:    "verbose",
+			overlay: &types.K8sObjectOverlay{Kind: "Secret", Name: "tls"},
+			verbose: true,
+			want: `overlay for Secret:tls does not match any object in output manifest. No similar objects found among 5 objects.
+Available objects are:
+Deployment:ns:central
+Deployment:ns:central-db
+Deployment:other:scanner
+Service:ns:centrl
+ConfigMap:ns:unrelated`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got := unmatchedOverlayError(tt.overlay, objs, "ns", tt.verbose)
+			if got.Error() != tt.want {
+				t.Errorf("%s: got:\n%s\nwant:\n%s", tt.desc, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package patch
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"github.com/stackrox/k8s-overlay-patch/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+// templateData is the data templated patch values are executed with.
+type templateData struct {
+	// Object is the matched object, before the patches of the overlay are applied.
+	Object map[string]any
+	// Namespace is the namespace of the object, or the default namespace if it has none.
+	Namespace string
+	// Vars are the variables set with WithVars.
+	Vars map[string]any
+}
+
+// newTemplateData returns the data for templated values of the patches applied to obj.
+func newTemplateData(obj *object.K8sObject, defaultNamespace string, vars map[string]any) *templateData {
+	ns := obj.Namespace
+	if ns == "" {
+		ns = defaultNamespace
+	}
+	return &templateData{Object: obj.Unstructured(), Namespace: ns, Vars: vars}
+}
+
+// templateFuncs are the functions available in templated values.
+var templateFuncs = template.FuncMap{
+	"default": defaultValue,
+	"quote":   func(v any) string { return strconv.Quote(toString(v)) },
+	"b64enc":  func(v any) string { return base64.StdEncoding.EncodeToString([]byte(toString(v))) },
+	"toYaml":  toYAML,
+	"sha256": func(v any) string {
+		sum := sha256.Sum256([]byte(toString(v)))
+		return hex.EncodeToString(sum[:])
+	},
+}
+
+// printFunc is the function appended to the pipelines of the actions of templated values, to write them. It writes nil,
+// such as the value of a missing map key, as empty, where text/template writes "<no value>".
+const printFunc = "toString"
+
+// parseTemplate parses the templated value text.
+func parseTemplate(text string) (*template.Template, error) {
+	t, err := template.New("value").Funcs(templateFuncs).Funcs(template.FuncMap{printFunc: toString}).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	for _, tt := range t.Templates() {
+		printActions(tt.Tree, tt.Root)
+	}
+	return t, nil
+}
+
+// printActions appends printFunc to the pipelines of the actions in node of tree that write their value.
+func printActions(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			printActions(tree, c)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) == 0 {
+			fn := parse.NewIdentifier(printFunc).SetTree(tree).SetPos(n.Pos)
+			n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pos, Args: []parse.Node{fn}})
+		}
+	case *parse.IfNode:
+		printActions(tree, n.List)
+		printActions(tree, n.ElseList)
+	case *parse.RangeNode:
+		printActions(tree, n.List)
+		printActions(tree, n.ElseList)
+	case *parse.WithNode:
+		printActions(tree, n.List)
+		printActions(tree, n.ElseList)
+	}
+}
+
+// expandTemplate returns p with its Value and Verbatim executed as templates with data, or p itself if it is not
+// templated.
+func expandTemplate(p *types.K8sObjectOverlayPatch, data *templateData) (*types.K8sObjectOverlayPatch, error) {
+	if !p.Template {
+		return p, nil
+	}
+	out := p.DeepCopy()
+	for _, text := range []*string{&out.Value, &out.Verbatim} {
+		if *text == "" {
+			continue
+		}
+		t, err := parseTemplate(*text)
+		if err != nil {
+			return nil, fmt.Errorf("path %s: %v", p.Path, err)
+		}
+		var b strings.Builder
+		if err := t.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("path %s: %v", p.Path, err)
+		}
+		*text = b.String()
+	}
+	return out, nil
+}
+
+// defaultValue returns v, or d if v is empty.
+func defaultValue(d, v any) any {
+	if v == nil {
+		return d
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice, reflect.String:
+		if rv.Len() == 0 {
+			return d
+		}
+	default:
+		if rv.IsZero() {
+			return d
+		}
+	}
+	return v
+}
+
+// toString returns v as text, with nil as the empty string.
+func toString(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// toYAML returns v as YAML without a trailing newline, so that it can be indented into other YAML.
+func toYAML(v any) (string, error) {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(b), "\n"), nil
+}
@@ -0,0 +1,75 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"github.com/stackrox/k8s-overlay-patch/pkg/openapi"
+	"github.com/stackrox/k8s-overlay-patch/pkg/util"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ObjectValidationError is the error for a patched object that does not conform to its schema or has invalid metadata.
+type ObjectValidationError struct {
+	// Object is the key of the object, as in Deployment:namespace:name.
+	Object string
+	// APIVersion is the apiVersion of the object, which its key leaves out.
+	APIVersion string
+	// Errors are the invalid fields of the object.
+	Errors field.ErrorList
+}
+
+// Error implements error.
+func (e *ObjectValidationError) Error() string {
+	return fmt.Sprintf("patched object %s is invalid: %v", e.Object, e.Errors.ToAggregate())
+}
+
+// ObjectValidationErrors returns the errors for invalid patched objects in err, an error returned by
+// YAMLManifestPatch with WithValidation.
+func ObjectValidationErrors(err error) []*ObjectValidationError {
+	switch e := err.(type) {
+	case *ObjectValidationError:
+		return []*ObjectValidationError{e}
+	case interface{ Unwrap() []error }:
+		var out []*ObjectValidationError
+		for _, ee := range e.Unwrap() {
+			out = append(out, ObjectValidationErrors(ee)...)
+		}
+		return out
+	case interface{ Unwrap() error }:
+		return ObjectValidationErrors(e.Unwrap())
+	}
+	return nil
+}
+
+// validateChangedObjects validates the objects of manifest, the patched and transformed manifest, against their schemas
+// from p, except for those that are in unchanged as their canonicalJSON.
+func validateChangedObjects(manifest string, unchanged map[string]bool, p openapi.Provider) util.Errors {
+	objs, err := object.ParseK8sObjectsFromYAMLManifest(manifest)
+	if err != nil {
+		return util.NewErrs(err)
+	}
+	var errs util.Errors
+	for _, obj := range objs {
+		if j, err := canonicalJSON(obj); err == nil && unchanged[j] {
+			continue
+		}
+		errs = util.AppendErr(errs, validateObject(obj, p))
+	}
+	return errs
+}
+
+// canonicalJSON returns the JSON of obj, which is equal for objects with equal content.
+func canonicalJSON(obj *object.K8sObject) (string, error) {
+	j, err := json.Marshal(obj.UnstructuredObject().Object)
+	return string(j), err
+}
+
+// validateObject validates obj against its schema from p.
+func validateObject(obj *object.K8sObject, p openapi.Provider) error {
+	if errs := openapi.ValidateObject(p.Schema(obj.GroupVersionKind()), obj.Unstructured()); len(errs) > 0 {
+		return &ObjectValidationError{Object: obj.Hash(), APIVersion: obj.UnstructuredObject().GetAPIVersion(), Errors: errs}
+	}
+	return nil
+}
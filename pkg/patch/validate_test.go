@@ -0,0 +1,107 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/openapi"
+	"github.com/stackrox/k8s-overlay-patch/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestYAMLManifestPatchObjectValidation(t *testing.T) {
+	base := `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    plural: widgets
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              color:
+                type: string
+                enum: [red, green]
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: w
+  namespace: ns
+spec:
+  color: red
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: d
+  namespace: ns
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: untouched
+  namespace: ns
+spec:
+  replica: 1
+`
+	overlays := []*types.K8sObjectOverlay{
+		{
+			Kind: "Deployment",
+			Name: "d",
+			Patches: []*types.K8sObjectOverlayPatch{
+				{Path: "spec.replicas", Value: "three"},
+				{Path: "metadata.labels", Value: "app: -d-"},
+			},
+		},
+		{
+			Kind: "Widget",
+			Name: "w",
+			Patches: []*types.K8sObjectOverlayPatch{
+				{Path: "spec.color", Value: "blue"},
+			},
+		},
+	}
+
+	_, err := YAMLManifestPatch(base, "ns", overlays)
+	require.NoError(t, err, "objects are not validated by default")
+
+	_, err = YAMLManifestPatch(base, "ns", overlays, WithValidation(openapi.Builtin()))
+	require.Error(t, err)
+	verrs := ObjectValidationErrors(err)
+	require.Len(t, verrs, 2)
+
+	assert.Equal(t, "Widget:ns:w", verrs[0].Object)
+	assert.Equal(t, "example.com/v1", verrs[0].APIVersion)
+	assert.EqualError(t, verrs[0], `patched object Widget:ns:w is invalid: spec.color: Unsupported value: "blue": supported values: "red", "green"`)
+
+	assert.Equal(t, "Deployment:ns:d", verrs[1].Object)
+	assert.Equal(t, "apps/v1", verrs[1].APIVersion)
+	require.Len(t, verrs[1].Errors, 2)
+	assert.Equal(t, `spec.replicas: Invalid value: "three": must be an integer`, verrs[1].Errors[0].Error())
+	assert.Contains(t, verrs[1].Errors[1].Error(), `metadata.labels: Invalid value: "-d-"`)
+
+	overlays[0].Patches = overlays[0].Patches[:0]
+	overlays[1].Patches[0].Value = "green"
+	_, err = YAMLManifestPatch(base, "ns", overlays, WithValidation(openapi.Builtin()))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,60 @@
+package patch
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/types"
+	"github.com/stackrox/k8s-overlay-patch/pkg/util"
+)
+
+// typedValue converts the value of a patch with an explicit type to that type. Scalars may be surrounded by
+// whitespace, the other types use value as written.
+func typedValue(typ, value string) (any, error) {
+	switch typ {
+	case types.ValueTypeString:
+		return value, nil
+	case types.ValueTypeInt:
+		i, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %q to int", value)
+		}
+		return i, nil
+	case types.ValueTypeBool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %q to bool", value)
+		}
+		return b, nil
+	case types.ValueTypeFloat:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %q to float", value)
+		}
+		return f, nil
+	case types.ValueTypeYAML:
+		v, err := util.UnmarshalValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid yaml value: %v", err)
+		}
+		return v, nil
+	case types.ValueTypeJSON:
+		if !json.Valid([]byte(value)) {
+			var v any
+			return nil, fmt.Errorf("invalid json value: %v", json.Unmarshal([]byte(value), &v))
+		}
+		// JSON is YAML, which keeps the precision of numbers.
+		return util.UnmarshalValue(value)
+	case types.ValueTypeBase64:
+		return base64.StdEncoding.EncodeToString([]byte(value)), nil
+	case types.ValueTypeNull:
+		if value != "" {
+			return nil, fmt.Errorf("value must be empty for type %s", typ)
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown value type %q, must be one of string, int, bool, float, yaml, json, base64 or null", typ)
+}
@@ -17,6 +17,9 @@
 // explicitly uses the legacy jsonpb package. This is due to a number of compatibility concerns with the new API:
 // * https://github.com/golang/protobuf/issues/1374
 // * https://github.com/golang/protobuf/issues/1373
+//
+// Deprecated: nothing in this module decodes protobuf messages anymore. The package is kept for existing importers and
+// will be removed in a future release.
 package protomarshal
 
 import (
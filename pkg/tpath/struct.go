@@ -27,10 +27,17 @@ import (
 )
 
 // GetFromStructPath returns the value at path from the given node, or false if the path does not exist.
+//
+// Deprecated: use GetFromStruct with a path from util.ParsePath, which reports malformed paths.
 func GetFromStructPath(node any, path string) (any, bool, error) {
 	return getFromStructPath(node, util.PathFromString(path))
 }
 
+// GetFromStruct returns the value at path from the given node, or false if the path does not exist.
+func GetFromStruct(node any, path util.Path) (any, bool, error) {
+	return getFromStructPath(node, path)
+}
+
 // getFromStructPath is the internal implementation of GetFromStructPath which recurses through a tree of Go structs
 // given a path. It terminates when the end of the path is reached or a path element does not exist.
 func getFromStructPath(node any, path util.Path) (any, bool, error) {
@@ -56,7 +63,7 @@ func getFromStructPath(node any, path util.Path) (any, bool, error) {
 		if path[0] == "" {
 			return nil, false, fmt.Errorf("getFromStructPath path %s, empty map key value", path)
 		}
-		mapVal := val.MapIndex(reflect.ValueOf(path[0]))
+		mapVal := val.MapIndex(reflect.ValueOf(util.PathKey(path[0])))
 		if !mapVal.IsValid() {
 			return nil, false, fmt.Errorf("getFromStructPath path %s, path does not exist", path)
 		}
@@ -98,7 +105,11 @@ func getFromStructPath(node any, path util.Path) (any, bool, error) {
 // All intermediate along path must be type struct ptr. Out must be either a struct ptr or map ptr.
 // TODO: move these out to a separate package (istio/istio#15494).
 func SetFromPath(node any, path string, out any) (bool, error) {
-	val, found, err := GetFromStructPath(node, path)
+	p, err := util.ParsePath(path)
+	if err != nil {
+		return false, err
+	}
+	val, found, err := GetFromStruct(node, p)
 	if err != nil {
 		return false, err
 	}
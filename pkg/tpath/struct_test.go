@@ -159,3 +159,22 @@ g:
 		})
 	}
 }
+
+func TestGetFromStruct(t *testing.T) {
+	node := map[string]any{"a": map[string]any{"b.c": "v"}}
+	got, found, err := GetFromStruct(node, mustPath(t, `a["b.c"]`))
+	if err != nil || !found || got != "v" {
+		t.Errorf("GetFromStruct: got:%v, found:%v, err:%v", got, found, err)
+	}
+
+	// The deprecated string form splits malformed paths instead of failing.
+	got, found, err = GetFromStructPath(map[string]any{"a": map[string]any{"b": "v"}}, "a..b")
+	if err != nil || !found || got != "v" {
+		t.Errorf("GetFromStructPath: got:%v, found:%v, err:%v", got, found, err)
+	}
+
+	var out map[string]any
+	if _, err := SetFromPath(node, "a..b", &out); err == nil {
+		t.Errorf("SetFromPath: expected a path syntax error")
+	}
+}
@@ -27,7 +27,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
-	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -62,13 +62,122 @@ func (nc *PathContext) String() string {
 	return ret
 }
 
+// pathMode controls how getPathContext treats path elements that do not exist in the tree.
+type pathMode int
+
+const (
+	// findOnly never modifies the tree. Missing path elements are reported as not found rather than as errors.
+	findOnly pathMode = iota
+	// createLeaf creates a missing leaf map entry, but requires all intermediate nodes to exist.
+	createLeaf
+	// createLeafOnly is like createLeaf, but also appends to a list for a leaf index past its end and creates the
+	// leaf under an intermediate node that exists but is null.
+	createLeafOnly
+	// createAll creates any missing map (but NOT list) entries along the path.
+	createAll
+)
+
+func modeFor(createMissing bool) pathMode {
+	if createMissing {
+		return createAll
+	}
+	return createLeaf
+}
+
 // GetPathContext returns the PathContext for the Node which has the given path from root.
 // It returns false and no error if the given path is not found, or an error code in other error situations, like
 // a malformed path.
 // It also creates a tree of PathContexts during the traversal so that Parent nodes can be updated if required. This is
 // required when (say) appending to a list, where the parent list itself must be updated.
 func GetPathContext(root any, path util.Path, createMissing bool) (*PathContext, bool, error) {
-	return getPathContext(&PathContext{Node: root}, path, path, createMissing)
+	return getPathContext(&PathContext{Node: root}, path, path, modeFor(createMissing))
+}
+
+// GetLeafPathContext is like GetPathContext with createMissing set to false, but only ever creates the leaf of the
+// path: a missing map entry, or a list element for an index past the end of the list. Errors for missing intermediate
+// nodes suggest existing keys with similar names.
+func GetLeafPathContext(root any, path util.Path) (*PathContext, bool, error) {
+	return getPathContext(&PathContext{Node: root}, path, path, createLeafOnly)
+}
+
+// FindPathContext returns the PathContext for the Node which has the given path from root, or false if any element
+// of the path, including the leaf, does not exist. Unlike GetPathContext, it never modifies the tree in root.
+func FindPathContext(root any, path util.Path) (*PathContext, bool, error) {
+	return getPathContext(&PathContext{Node: root}, path, path, findOnly)
+}
+
+// ExpandPath returns the concrete paths that path resolves to in root, replacing each wildcard element with the
+// index of every element of the selected list ([*]) or the key of every entry of the selected map (*). Map keys are
+// replaced as util.KeyPathElement returns them, so that a key named * is not expanded again; to address such a key
+// without expanding it, quote it, as in a["*"]. Entries of maps with keys that are not strings cannot be addressed by
+// a path and are skipped.
+// Path elements before a wildcard must exist for the wildcard to match anything. Path elements after the last
+// wildcard are kept as they are, and are resolved like those of any other path when the returned paths are used,
+// which may create missing nodes.
+// A path without wildcards is returned unchanged. A wildcard that selects a missing or empty node yields no paths.
+// Paths are returned in tree order, so writing them in reverse order is safe even if writes delete list elements.
+func ExpandPath(root any, path util.Path) ([]util.Path, error) {
+	idx := -1
+	for i := range path {
+		if util.IsWildcardPathElement(path[i]) {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return []util.Path{path}, nil
+	}
+
+	node := root
+	if idx > 0 {
+		nc, found, err := FindPathContext(root, path[:idx])
+		if err != nil || !found {
+			return nil, err
+		}
+		node = nc.Node
+	}
+	if p, ok := node.(*any); ok {
+		node = *p
+	}
+
+	var elements []string
+	switch n := node.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		if path[idx] != util.ListWildcard {
+			return nil, fmt.Errorf("path %s: wildcard %s selects a list, use %s", path, path[idx], util.ListWildcard)
+		}
+		for i := range n {
+			elements = append(elements, fmt.Sprintf("[%d]", i))
+		}
+	case map[string]any, map[any]any:
+		if path[idx] != util.MapWildcard {
+			return nil, fmt.Errorf("path %s: wildcard %s selects a map, use %s", path, path[idx], util.MapWildcard)
+		}
+		for _, k := range reflect.ValueOf(n).MapKeys() {
+			if key, ok := k.Interface().(string); ok {
+				elements = append(elements, util.KeyPathElement(key))
+			}
+		}
+		sort.Strings(elements)
+	default:
+		return nil, fmt.Errorf("path %s: wildcard %s selects leaf type %T", path, path[idx], node)
+	}
+
+	var out []util.Path
+	for _, pe := range elements {
+		concrete := make(util.Path, 0, len(path))
+		concrete = append(concrete, path[:idx]...)
+		concrete = append(concrete, pe)
+		concrete = append(concrete, path[idx+1:]...)
+		expanded, err := ExpandPath(root, concrete)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, expanded...)
+	}
+	return out, nil
 }
 
 // WritePathContext writes the given value to the Node in the given PathContext.
@@ -101,9 +210,19 @@ func WritePathContext(nc *PathContext, value any, merge bool, tryUnmarshal bool)
 	return fmt.Errorf("cannot delete path: unsupported parent type %T for delete", nc.Parent.Node)
 }
 
+// WriteNullPathContext writes an explicit null to the Node in the given PathContext. Unlike writing a nil value with
+// WritePathContext, it does not delete the node, and it replaces lists instead of appending to them.
+func WriteNullPathContext(nc *PathContext) error {
+	if nc.Parent == nil {
+		return errors.New("cannot set root element to null")
+	}
+	nc.Node = nil
+	return setPathContext(nc, nil, false, false)
+}
+
 // WriteNode writes value to the tree in root at the given path, creating any required missing internal nodes in path.
 func WriteNode(root any, path util.Path, value any) error {
-	pc, _, err := getPathContext(&PathContext{Node: root}, path, path, true)
+	pc, _, err := getPathContext(&PathContext{Node: root}, path, path, createAll)
 	if err != nil {
 		return err
 	}
@@ -112,7 +231,7 @@ func WriteNode(root any, path util.Path, value any) error {
 
 // MergeNode merges value to the tree in root at the given path, creating any required missing internal nodes in path.
 func MergeNode(root any, path util.Path, value any) error {
-	pc, _, err := getPathContext(&PathContext{Node: root}, path, path, true)
+	pc, _, err := getPathContext(&PathContext{Node: root}, path, path, createAll)
 	if err != nil {
 		return err
 	}
@@ -133,24 +252,30 @@ func Find(inputTree map[string]any, path util.Path) (any, bool, error) {
 
 // Delete sets value at path of input untyped tree to nil
 func Delete(root map[string]any, path util.Path) (bool, error) {
-	pc, _, err := getPathContext(&PathContext{Node: root}, path, path, false)
+	pc, _, err := getPathContext(&PathContext{Node: root}, path, path, createLeaf)
 	if err != nil {
 		return false, err
 	}
 	return true, WritePathContext(pc, nil, false, true)
 }
 
-// getPathContext is the internal implementation of GetPathContext.
-// If createMissing is true, it creates any missing map (but NOT list) path entries in root.
-func getPathContext(nc *PathContext, fullPath, remainPath util.Path, createMissing bool) (*PathContext, bool, error) {
+// getPathContext is the internal implementation of GetPathContext and FindPathContext.
+// mode determines whether missing path entries are created in root, see pathMode.
+func getPathContext(nc *PathContext, fullPath, remainPath util.Path, mode pathMode) (*PathContext, bool, error) {
 	//scope.Debugf("getPathContext remainPath=%s, Node=%v", remainPath, nc.Node)
 	if len(remainPath) == 0 {
 		return nc, true, nil
 	}
 	pe := remainPath[0]
+	if util.IsWildcardPathElement(pe) {
+		return nil, false, fmt.Errorf("path %s: wildcard %s must be expanded with ExpandPath", fullPath, pe)
+	}
 
 	if nc.Node == nil {
-		if !createMissing {
+		if mode == findOnly {
+			return nil, false, nil
+		}
+		if mode != createAll && (mode != createLeafOnly || len(remainPath) > 1) {
 			return nil, false, fmt.Errorf("node %s is zero", pe)
 		}
 		if util.IsNPathElement(pe) || util.IsKVPathElement(pe) {
@@ -179,7 +304,10 @@ func getPathContext(nc *PathContext, fullPath, remainPath util.Path, createMissi
 			}
 			var foundNode any
 			if idx >= len(lst) || idx < 0 {
-				if !createMissing {
+				if mode == findOnly {
+					return nil, false, nil
+				}
+				if mode != createAll && (mode != createLeafOnly || len(remainPath) > 1) {
 					return nil, false, fmt.Errorf("index %d exceeds list length %d at path %s", idx, len(lst), remainPath)
 				}
 				idx = len(lst)
@@ -192,110 +320,108 @@ func getPathContext(nc *PathContext, fullPath, remainPath util.Path, createMissi
 				Node:   foundNode,
 			}
 			nc.KeyToChild = idx
-			return getPathContext(nn, fullPath, remainPath[1:], createMissing)
+			return getPathContext(nn, fullPath, remainPath[1:], mode)
 		}
 
-		// Otherwise the path element must have form [key:value]. In this case, go through all list elements, which
-		// must have map type, and try to find one which has a matching key:value.
+		// Otherwise the path element must have form [key:value] or [key1:value1,key2:value2]. In this case, go through
+		// all list elements, which must have map type, and try to find one which has all matching key:value pairs.
 		for idx, le := range lst {
 			// non-leaf list, expect to match item by key:value.
-			if lm, ok := le.(map[any]any); ok {
-				k, v, err := util.PathKV(pe)
+			if util.IsMap(le) {
+				kvs, err := util.PathKVs(pe)
 				if err != nil {
 					return nil, false, fmt.Errorf("path %s: %s", fullPath, err)
 				}
-				if stringsEqual(lm[k], v) {
-					//scope.Debugf("found matching kv %v:%v", k, v)
-					nn := &PathContext{
-						Parent: nc,
-						Node:   lm,
-					}
-					nc.KeyToChild = idx
-					nn.KeyToChild = k
-					if len(remainPath) == 1 {
-						//scope.Debug("KV terminate")
-						return nn, true, nil
-					}
-					return getPathContext(nn, fullPath, remainPath[1:], createMissing)
-				}
-				continue
-			}
-			// repeat of the block above for the case where tree unmarshals to map[string]interface{}. There doesn't
-			// seem to be a way to merge this case into the above block.
-			if lm, ok := le.(map[string]any); ok {
-				k, v, err := util.PathKV(pe)
+				match, err := matchesKVs(le, kvs)
 				if err != nil {
 					return nil, false, fmt.Errorf("path %s: %s", fullPath, err)
 				}
-				if stringsEqual(lm[k], v) {
-					//scope.Debugf("found matching kv %v:%v", k, v)
+				if match {
+					//scope.Debugf("found matching kv %v", kvs)
 					nn := &PathContext{
 						Parent: nc,
-						Node:   lm,
+						Node:   le,
 					}
 					nc.KeyToChild = idx
-					nn.KeyToChild = k
+					nn.KeyToChild = kvs[0].Key.String()
 					if len(remainPath) == 1 {
 						//scope.Debug("KV terminate")
 						return nn, true, nil
 					}
-					return getPathContext(nn, fullPath, remainPath[1:], createMissing)
+					return getPathContext(nn, fullPath, remainPath[1:], mode)
 				}
 				continue
 			}
-			// leaf list, expect path element [V], match based on value V.
-			v, err := util.PathV(pe)
+			// leaf list, expect path element [V] or [<op>V], match based on value V.
+			op, v, err := util.PathVMatch(pe)
 			if err != nil {
 				return nil, false, fmt.Errorf("path %s: %s", fullPath, err)
 			}
-			if matchesRegex(v, le) {
+			match, err := util.MatchValue(op, v, fmt.Sprint(le))
+			if err != nil {
+				return nil, false, fmt.Errorf("path %s: %s", fullPath, err)
+			}
+			if match {
 				//scope.Debugf("found matching key %v, index %d", le, idx)
 				nn := &PathContext{
 					Parent: nc,
 					Node:   le,
 				}
 				nc.KeyToChild = idx
-				return getPathContext(nn, fullPath, remainPath[1:], createMissing)
+				return getPathContext(nn, fullPath, remainPath[1:], mode)
 			}
 		}
+		if mode == findOnly {
+			return nil, false, nil
+		}
 		return nil, false, fmt.Errorf("path %s: element %s not found", fullPath, pe)
 	}
 
 	if util.IsMap(ncNode) {
 		//scope.Debug("map type")
 		var nn any
+		key := util.PathKey(pe)
 		if m, ok := ncNode.(map[any]any); ok {
-			nn, ok = m[pe]
+			nn, ok = m[key]
 			if !ok {
 				// remainPath == 1 means the patch is creation of a new leaf.
-				if createMissing || len(remainPath) == 1 {
-					m[pe] = make(map[any]any)
-					nn = m[pe]
+				if mode == findOnly {
+					return nil, false, nil
+				}
+				if mode == createAll || len(remainPath) == 1 {
+					m[key] = make(map[any]any)
+					nn = m[key]
 				} else {
-					return nil, false, fmt.Errorf("path not found at element %s in path %s", pe, fullPath)
+					return nil, false, notFoundError(key, fullPath, m)
 				}
 			}
 		}
 		if reflect.ValueOf(ncNode).IsNil() {
+			if mode == findOnly {
+				return nil, false, nil
+			}
 			ncNode = make(map[string]any)
 			nc.Node = ncNode
 		}
 		if m, ok := ncNode.(map[string]any); ok {
-			nn, ok = m[pe]
+			nn, ok = m[key]
 			if !ok {
 				// remainPath == 1 means the patch is creation of a new leaf.
-				if createMissing || len(remainPath) == 1 {
+				if mode == findOnly {
+					return nil, false, nil
+				}
+				if mode == createAll || len(remainPath) == 1 {
 					nextElementNPath := len(remainPath) > 1 && util.IsNPathElement(remainPath[1])
 					if nextElementNPath {
 						//scope.Debug("map type, slice child")
-						m[pe] = make([]any, 0)
+						m[key] = make([]any, 0)
 					} else {
 						//scope.Debug("map type, map child")
-						m[pe] = make(map[string]any)
+						m[key] = make(map[string]any)
 					}
-					nn = m[pe]
+					nn = m[key]
 				} else {
-					return nil, false, fmt.Errorf("path not found at element %s in path %s", pe, fullPath)
+					return nil, false, notFoundError(key, fullPath, m)
 				}
 			}
 		}
@@ -308,13 +434,29 @@ func getPathContext(nc *PathContext, fullPath, remainPath util.Path, createMissi
 		if util.IsSlice(nn) {
 			npc.Node = &nn
 		}
-		nc.KeyToChild = pe
-		return getPathContext(npc, fullPath, remainPath[1:], createMissing)
+		nc.KeyToChild = key
+		return getPathContext(npc, fullPath, remainPath[1:], mode)
 	}
 
+	if mode == findOnly {
+		return nil, false, nil
+	}
 	return nil, false, fmt.Errorf("leaf type %T in non-leaf Node %s", nc.Node, remainPath)
 }
 
+// notFoundError returns the error for the missing map entry pe of path, suggesting the keys of m that are closest to
+// pe, if any are close enough to be a likely typo.
+func notFoundError(pe string, path util.Path, m any) error {
+	var keys []string
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, fmt.Sprint(k.Interface()))
+	}
+	if similar := util.ClosestStrings(pe, keys); len(similar) > 0 {
+		return fmt.Errorf("path not found at element %s in path %s, did you mean %s?", pe, path, util.QuoteList(similar, "or"))
+	}
+	return fmt.Errorf("path not found at element %s in path %s", pe, path)
+}
+
 // setPathContext writes the given value to the Node in the given PathContext,
 // enlarging all PathContext lists to ensure all indexes are valid.
 func setPathContext(nc *PathContext, value any, merge bool, tryUnmarshal bool) error {
@@ -503,20 +645,20 @@ func find(treeNode any, path util.Path) (any, bool) {
 	}
 }
 
-// stringsEqual reports whether the string representations of a and b are equal. a and b may have different types.
-func stringsEqual(a, b any) bool {
-	return fmt.Sprint(a) == fmt.Sprint(b)
-}
-
-// matchesRegex reports whether str regex matches pattern.
-func matchesRegex(pattern, str any) bool {
-	match, err := regexp.MatchString(fmt.Sprint(pattern), fmt.Sprint(str))
-	if err != nil {
-		//log.Errorf("bad regex expression %s", fmt.Sprint(pattern))
-		return false
+// matchesKVs reports whether the list element le has all the key/value pairs in kvs. Keys may refer to nested map
+// entries of le.
+func matchesKVs(le any, kvs []util.PathKeyValue) (bool, error) {
+	for _, kv := range kvs {
+		v, found := find(le, kv.Key)
+		if !found {
+			return false, nil
+		}
+		match, err := util.MatchValue(kv.Op, kv.Value, fmt.Sprint(v))
+		if err != nil || !match {
+			return false, err
+		}
 	}
-	//scope.Debugf("%v regex %v? %v\n", pattern, str, match)
-	return match
+	return true, nil
 }
 
 // isSliceOrPtrInterface reports whether v is a slice, a ptr to slice or interface to slice.
@@ -547,18 +689,19 @@ func tryToUnmarshalStringToYAML(s any) (any, bool) {
 	// to discover it's the latter.
 	vv := s
 
-	if reflect.TypeOf(vv).Kind() == reflect.String {
-		sv := strings.Split(vv.(string), "\n")
+	// Only plain strings, other string kinds such as util.Number are never YAML.
+	if str, ok := vv.(string); ok {
+		sv := strings.Split(str, "\n")
 		// Need to be careful not to transform string literals into maps unless they really are maps, since scalar handling
 		// is different for inserts.
-		if len(sv) == 1 && strings.Contains(s.(string), ": ") ||
-			len(sv) > 1 && strings.Contains(s.(string), ":") {
+		if len(sv) == 1 && strings.Contains(str, ": ") ||
+			len(sv) > 1 && strings.Contains(str, ":") {
 			nv := make(map[string]any)
-			if err := json.Unmarshal([]byte(vv.(string)), &nv); err == nil {
+			if err := json.Unmarshal([]byte(str), &nv); err == nil {
 				// treat JSON as string
 				return vv, false
 			}
-			if err := yaml2.Unmarshal([]byte(vv.(string)), &nv); err == nil {
+			if err := yaml2.Unmarshal([]byte(str), &nv); err == nil {
 				return nv, true
 			}
 		}
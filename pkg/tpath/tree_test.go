@@ -15,6 +15,7 @@
 package tpath
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/stackrox/k8s-overlay-patch/pkg/util"
@@ -319,9 +320,9 @@ a: {}
 		},
 		{
 			desc:      "error key",
-			path:      `a.b.[].list`,
+			path:      `a.b.[:].list`,
 			wantFound: false,
-			wantErr:   `path a.b.[].list: [] is not a valid key:value path element`,
+			wantErr:   `path a.b.[:].list: [:] is not a valid key:value path element`,
 		},
 		{
 			desc:      "invalid index",
@@ -336,7 +337,7 @@ a: {}
 			if err := yaml.Unmarshal([]byte(rootYAML), &root); err != nil {
 				t.Fatal(err)
 			}
-			pc, gotFound, gotErr := GetPathContext(root, util.PathFromString(tt.path), false)
+			pc, gotFound, gotErr := GetPathContext(root, mustPath(t, tt.path), false)
 			if gotErr, wantErr := errToString(gotErr), tt.wantErr; gotErr != wantErr {
 				t.Fatalf("GetPathContext(%s): gotErr:%s, wantErr:%s", tt.desc, gotErr, wantErr)
 			}
@@ -579,7 +580,7 @@ a:
 					t.Fatal(err)
 				}
 			}
-			p := util.PathFromString(tt.path)
+			p := mustPath(t, tt.path)
 			err := WriteNode(root, p, tt.value)
 			if gotErr, wantErr := errToString(err), tt.wantErr; gotErr != wantErr {
 				t.Errorf("%s: gotErr:%s, wantErr:%s", tt.desc, gotErr, wantErr)
@@ -653,7 +654,7 @@ a:
 					t.Fatal(err)
 				}
 			}
-			p := util.PathFromString(tt.path)
+			p := mustPath(t, tt.path)
 			iv := make(map[string]any)
 			err := yaml.Unmarshal([]byte(tt.value), &iv)
 			if err != nil {
@@ -748,7 +749,7 @@ values:
 
 	for _, override := range overrides {
 
-		pc, _, err := GetPathContext(root, util.PathFromString(override.path), true)
+		pc, _, err := GetPathContext(root, mustPath(t, override.path), true)
 		if err != nil {
 			t.Fatalf("GetPathContext(%q): %v", override.path, err)
 		}
@@ -817,7 +818,7 @@ values:
 			if err := yaml.Unmarshal([]byte(rootYAML), &root); err != nil {
 				t.Fatal(err)
 			}
-			pc, gotFound, gotErr := GetPathContext(root, util.PathFromString(tt.path), false)
+			pc, gotFound, gotErr := GetPathContext(root, mustPath(t, tt.path), false)
 			if gotErr, wantErr := errToString(gotErr), tt.wantErr; gotErr != wantErr {
 				t.Fatalf("GetPathContext(%s): gotErr:%s, wantErr:%s", tt.desc, gotErr, wantErr)
 			}
@@ -841,3 +842,564 @@ values:
 		})
 	}
 }
+
+func TestFindPathContext(t *testing.T) {
+	rootYAML := `
+a:
+  b:
+  - name: n1
+    value: v1
+  - name: n2
+    list:
+    - v1
+    - v2
+  c:
+`
+	tests := []struct {
+		desc      string
+		path      string
+		want      any
+		wantFound bool
+		wantErr   string
+	}{
+		{
+			desc:      "leaf",
+			path:      `a.b.[name:n1].value`,
+			want:      "v1",
+			wantFound: true,
+		},
+		{
+			desc:      "list value",
+			path:      `a.b.[name:n2].list.[v2]`,
+			want:      "v2",
+			wantFound: true,
+		},
+		{
+			desc: "missing leaf",
+			path: `a.b.[name:n1].other`,
+		},
+		{
+			desc: "missing intermediate",
+			path: `a.d.e`,
+		},
+		{
+			desc: "null node",
+			path: `a.c.d`,
+		},
+		{
+			desc: "missing list element",
+			path: `a.b.[name:n3].value`,
+		},
+		{
+			desc: "index out of range",
+			path: `a.b.[5]`,
+		},
+		{
+			desc: "below scalar",
+			path: `a.b.[name:n1].value.x`,
+		},
+		{
+			desc:    "error key",
+			path:    `a.b.[:].list`,
+			wantErr: `path a.b.[:].list: [:] is not a valid key:value path element`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			root := make(map[string]any)
+			if err := yaml.Unmarshal([]byte(rootYAML), &root); err != nil {
+				t.Fatal(err)
+			}
+			before := util.ToYAML(root)
+			pc, gotFound, gotErr := FindPathContext(root, mustPath(t, tt.path))
+			if gotErr, wantErr := errToString(gotErr), tt.wantErr; gotErr != wantErr {
+				t.Fatalf("FindPathContext(%s): gotErr:%s, wantErr:%s", tt.desc, gotErr, wantErr)
+			}
+			if gotFound != tt.wantFound {
+				t.Fatalf("FindPathContext(%s): gotFound:%v, wantFound:%v", tt.desc, gotFound, tt.wantFound)
+			}
+			if gotFound && pc.Node != tt.want {
+				t.Errorf("FindPathContext(%s): got:%v, want:%v", tt.desc, pc.Node, tt.want)
+			}
+			if diff := util.YAMLDiff(before, util.ToYAML(root)); diff != "" {
+				t.Errorf("FindPathContext(%s) modified the tree:\n%s\n", tt.desc, diff)
+			}
+		})
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	rootYAML := `
+a:
+  b:
+  - name: n1
+    list:
+    - v1
+  - name: n2
+    list:
+    - v1
+    - v2
+  m:
+    k2: 1
+    k1: 2
+  q:
+    "*": 1
+    k: 2
+  c:
+  s: scalar
+`
+	tests := []struct {
+		desc    string
+		path    string
+		want    []string
+		wantErr string
+	}{
+		{
+			desc: "no wildcard",
+			path: `a.d.e`,
+			want: []string{`a.d.e`},
+		},
+		{
+			desc: "list",
+			path: `a.b.[*].name`,
+			want: []string{`a.b.[0].name`, `a.b.[1].name`},
+		},
+		{
+			desc: "nested lists",
+			path: `a.b.[*].list.[*]`,
+			want: []string{`a.b.[0].list.[0]`, `a.b.[1].list.[0]`, `a.b.[1].list.[1]`},
+		},
+		{
+			desc: "selector before wildcard",
+			path: `a.b.[name:n2].list.[*]`,
+			want: []string{`a.b.[name:n2].list.[0]`, `a.b.[name:n2].list.[1]`},
+		},
+		{
+			desc: "map keys are sorted",
+			path: `a.m.*`,
+			want: []string{`a.m.k1`, `a.m.k2`},
+		},
+		{
+			desc: "key named wildcard is not expanded again",
+			path: `a.q.*`,
+			want: []string{`a.q.["*"]`, `a.q.k`},
+		},
+		{
+			desc: "quoted wildcard is a key",
+			path: `a.q.["*"]`,
+			want: []string{`a.q.["*"]`},
+		},
+		{
+			desc: "missing elements after wildcard",
+			path: `a.m.*.x.y`,
+			want: []string{`a.m.k1.x.y`, `a.m.k2.x.y`},
+		},
+		{
+			desc: "missing node",
+			path: `a.d.[*]`,
+		},
+		{
+			desc: "null node",
+			path: `a.c.*`,
+		},
+		{
+			desc:    "list wildcard on map",
+			path:    `a.m.[*]`,
+			wantErr: `path a.m.[*]: wildcard [*] selects a map, use *`,
+		},
+		{
+			desc:    "map wildcard on list",
+			path:    `a.b.*`,
+			wantErr: `path a.b.*: wildcard * selects a list, use [*]`,
+		},
+		{
+			desc:    "scalar",
+			path:    `a.s.*`,
+			wantErr: `path a.s.*: wildcard * selects leaf type string`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			root := make(map[string]any)
+			if err := yaml.Unmarshal([]byte(rootYAML), &root); err != nil {
+				t.Fatal(err)
+			}
+			got, gotErr := ExpandPath(root, mustPath(t, tt.path))
+			if gotErr, wantErr := errToString(gotErr), tt.wantErr; gotErr != wantErr {
+				t.Fatalf("ExpandPath(%s): gotErr:%s, wantErr:%s", tt.desc, gotErr, wantErr)
+			}
+			var gotStrings []string
+			for _, p := range got {
+				gotStrings = append(gotStrings, p.String())
+			}
+			if !reflect.DeepEqual(gotStrings, tt.want) {
+				t.Errorf("ExpandPath(%s): got:%v, want:%v", tt.desc, gotStrings, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandPathNonStringKeys(t *testing.T) {
+	root := map[string]any{
+		"a": map[any]any{1: "int", "k": "string"},
+	}
+	got, err := ExpandPath(root, mustPath(t, `a.*`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []util.Path{{"a", "k"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpandPath: got:%v, want:%v", got, want)
+	}
+}
+
+func TestKVSelectors(t *testing.T) {
+	rootYAML := `
+ports:
+- name: grpc
+  protocol: UDP
+  port: 1
+- name: grpc
+  protocol: TCP
+  port: 2
+env:
+- name: A
+  value: a
+- name: B
+  valueFrom:
+    secretKeyRef:
+      name: foo
+      key: b
+args:
+- --log-level=info
+- --port=1
+`
+	tests := []struct {
+		desc      string
+		path      string
+		want      any
+		wantFound bool
+		wantErr   string
+	}{
+		{
+			desc:      "single key selects first match",
+			path:      `ports.[name:grpc].port`,
+			want:      1.0,
+			wantFound: true,
+		},
+		{
+			desc:      "compound",
+			path:      `ports.[name:grpc,protocol:TCP].port`,
+			want:      2.0,
+			wantFound: true,
+		},
+		{
+			desc:      "compound with numbers",
+			path:      `ports.[port:2,protocol:TCP].name`,
+			want:      "grpc",
+			wantFound: true,
+		},
+		{
+			desc:      "nested",
+			path:      `env.[valueFrom.secretKeyRef.name:foo].name`,
+			want:      "B",
+			wantFound: true,
+		},
+		{
+			desc:      "nested and compound",
+			path:      `env.[valueFrom.secretKeyRef.name:foo,valueFrom.secretKeyRef.key:b].name`,
+			want:      "B",
+			wantFound: true,
+		},
+		{
+			desc: "compound no match",
+			path: `ports.[name:grpc,protocol:SCTP].port`,
+		},
+		{
+			desc: "nested missing",
+			path: `env.[valueFrom.configMapKeyRef.name:foo].name`,
+		},
+		{
+			desc:      "exact operator",
+			path:      `ports.[name=grpc,protocol=TCP].port`,
+			want:      2.0,
+			wantFound: true,
+		},
+		{
+			desc: "exact operator is not a substring match",
+			path: `ports.[name=grp].port`,
+		},
+		{
+			desc:      "prefix operator",
+			path:      `ports.[protocol^=T].port`,
+			want:      2.0,
+			wantFound: true,
+		},
+		{
+			desc:      "glob operator",
+			path:      `env.[valueFrom.secretKeyRef.name*=f?o].name`,
+			want:      "B",
+			wantFound: true,
+		},
+		{
+			desc:      "regex operator",
+			path:      `ports.[protocol~=TCP|SCTP].port`,
+			want:      2.0,
+			wantFound: true,
+		},
+		{
+			desc: "regex operator is anchored",
+			path: `ports.[protocol~=C].port`,
+		},
+		{
+			desc:      "leaf prefix operator",
+			path:      `args.[^=--port=]`,
+			want:      "--port=1",
+			wantFound: true,
+		},
+		{
+			desc:      "leaf exact operator",
+			path:      `args.[=--log-level=info]`,
+			want:      "--log-level=info",
+			wantFound: true,
+		},
+		{
+			desc: "leaf regex operator is anchored",
+			path: `args.[~=port]`,
+		},
+		{
+			desc:    "invalid regex",
+			path:    `ports.[protocol~=(].port`,
+			wantErr: "path ports.[protocol~=(].port: invalid regular expression \"(\": error parsing regexp: missing closing ): `(`",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			root := make(map[string]any)
+			if err := yaml.Unmarshal([]byte(rootYAML), &root); err != nil {
+				t.Fatal(err)
+			}
+			pc, gotFound, gotErr := FindPathContext(root, mustPath(t, tt.path))
+			if gotErr, wantErr := errToString(gotErr), tt.wantErr; gotErr != wantErr {
+				t.Fatalf("FindPathContext(%s): gotErr:%s, wantErr:%s", tt.desc, gotErr, wantErr)
+			}
+			if gotFound != tt.wantFound {
+				t.Fatalf("FindPathContext(%s): gotFound:%v, wantFound:%v", tt.desc, gotFound, tt.wantFound)
+			}
+			if gotFound && !reflect.DeepEqual(pc.Node, tt.want) {
+				t.Errorf("FindPathContext(%s): got:%v(%T), want:%v(%T)", tt.desc, pc.Node, pc.Node, tt.want, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetLeafPathContext(t *testing.T) {
+	rootYAML := `
+spec:
+  template:
+    spec:
+      nodeSelector: null
+      containers:
+      - name: app
+        args:
+        - a
+`
+	tests := []struct {
+		desc    string
+		path    string
+		value   any
+		want    string
+		wantErr string
+	}{
+		{
+			desc:  "create leaf",
+			path:  `spec.template.spec.hostNetwork`,
+			value: true,
+			want: `
+spec:
+  template:
+    spec:
+      hostNetwork: true
+      nodeSelector: null
+      containers:
+      - name: app
+        args:
+        - a
+`,
+		},
+		{
+			desc:  "create leaf under null node",
+			path:  `spec.template.spec.nodeSelector.disk`,
+			value: "ssd",
+			want: `
+spec:
+  template:
+    spec:
+      nodeSelector:
+        disk: ssd
+      containers:
+      - name: app
+        args:
+        - a
+`,
+		},
+		{
+			desc:  "append to list",
+			path:  `spec.template.spec.containers.[name:app].args.[1000]`,
+			value: "b",
+			want: `
+spec:
+  template:
+    spec:
+      nodeSelector: null
+      containers:
+      - name: app
+        args:
+        - a
+        - b
+`,
+		},
+		{
+			desc:    "typo",
+			path:    `spec.tempalte.spec.nodeSelector`,
+			wantErr: "path not found at element tempalte in path spec.tempalte.spec.nodeSelector, did you mean `template`?",
+		},
+		{
+			desc:    "missing intermediate node",
+			path:    `spec.template.spec.securityContext.runAsUser`,
+			wantErr: "path not found at element securityContext in path spec.template.spec.securityContext.runAsUser",
+		},
+		{
+			desc:    "index past end of intermediate list",
+			path:    `spec.template.spec.containers.[1].name`,
+			wantErr: "index 1 exceeds list length 1 at path [1].name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			root := make(map[string]any)
+			if err := yaml.Unmarshal([]byte(rootYAML), &root); err != nil {
+				t.Fatal(err)
+			}
+			pc, _, err := GetLeafPathContext(root, mustPath(t, tt.path))
+			if gotErr := errToString(err); gotErr != tt.wantErr {
+				t.Fatalf("GetLeafPathContext(%s): gotErr:%s, wantErr:%s", tt.desc, gotErr, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if err := WritePathContext(pc, tt.value, false, false); err != nil {
+				t.Fatal(err)
+			}
+			if diff := util.YAMLDiff(util.ToYAML(root), tt.want); diff != "" {
+				t.Errorf("%s: diff:\n%s\n", tt.desc, diff)
+			}
+		})
+	}
+}
+
+func TestWriteNullPathContext(t *testing.T) {
+	rootYAML := `
+metadata:
+  annotations:
+    a: b
+spec:
+  containers:
+  - name: app
+    args:
+    - a
+    - b
+`
+	tests := []struct {
+		desc string
+		path string
+		want string
+	}{
+		{
+			desc: "existing leaf",
+			path: `metadata.annotations.a`,
+			want: `
+metadata:
+  annotations:
+    a: null
+spec:
+  containers:
+  - name: app
+    args:
+    - a
+    - b
+`,
+		},
+		{
+			desc: "new leaf",
+			path: `metadata.labels`,
+			want: `
+metadata:
+  annotations:
+    a: b
+  labels: null
+spec:
+  containers:
+  - name: app
+    args:
+    - a
+    - b
+`,
+		},
+		{
+			desc: "list",
+			path: `spec.containers.[name:app].args`,
+			want: `
+metadata:
+  annotations:
+    a: b
+spec:
+  containers:
+  - name: app
+    args: null
+`,
+		},
+		{
+			desc: "list element",
+			path: `spec.containers.[name:app].args.[1]`,
+			want: `
+metadata:
+  annotations:
+    a: b
+spec:
+  containers:
+  - name: app
+    args:
+    - a
+    - null
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			root := make(map[string]any)
+			if err := yaml.Unmarshal([]byte(rootYAML), &root); err != nil {
+				t.Fatal(err)
+			}
+			pc, _, err := GetLeafPathContext(root, mustPath(t, tt.path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := WriteNullPathContext(pc); err != nil {
+				t.Fatal(err)
+			}
+			if diff := util.YAMLDiff(util.ToYAML(root), tt.want); diff != "" {
+				t.Errorf("%s: diff:\n%s\n", tt.desc, diff)
+			}
+		})
+	}
+}
+
+// mustPath returns the path s, failing t if it does not parse.
+func mustPath(t *testing.T, s string) util.Path {
+	t.Helper()
+	p, err := util.ParsePath(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
@@ -50,7 +50,11 @@ func GetConfigSubtree(manifest, path string) (string, error) {
 		return "", err
 	}
 
-	nc, _, err := GetPathContext(root, util.PathFromString(path), false)
+	p, err := util.ParsePath(path)
+	if err != nil {
+		return "", err
+	}
+	nc, _, err := GetPathContext(root, p, false)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,138 @@
+package transform
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	names "github.com/stackrox/k8s-overlay-patch/pkg/name"
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ChecksumAnnotationPrefix is the prefix of the pod template annotations that ConfigChecksums writes.
+const ChecksumAnnotationPrefix = "checksum/"
+
+// annotationNameMaxLength is the maximum length of the name part of annotation keys, after the prefix.
+const annotationNameMaxLength = 63
+
+// ConfigChecksums returns a Transformer that annotates the pod template of each workload with the checksum of every
+// ConfigMap and Secret of the manifest it references through volumes, envFrom or env valueFrom. The annotations for
+// the ConfigMap and the Secret named name are checksum/configmap-<name> and checksum/secret-<name>, so that changing
+// the data of a ConfigMap or Secret changes the pod templates that use it, which rolls out their pods. References to
+// objects that are not in the manifest are ignored.
+//
+// Objects without a namespace are in namespace, the default namespace, so a workload without a namespace uses the
+// ConfigMaps and Secrets of namespace, whether their namespace is set or not.
+func ConfigChecksums(namespace string) Transformer {
+	return TransformerFunc(func(objs object.K8sObjects) (object.K8sObjects, error) {
+		return configChecksums(objs, namespace)
+	})
+}
+
+func configChecksums(objs object.K8sObjects, defaultNamespace string) (object.K8sObjects, error) {
+	namespaceOf := func(obj *object.K8sObject) string {
+		if obj.Namespace == "" {
+			return defaultNamespace
+		}
+		return obj.Namespace
+	}
+	checksums := make(map[objectRef]string)
+	for _, obj := range objs {
+		if obj.Kind != names.ConfigMapStr && obj.Kind != names.SecretStr {
+			continue
+		}
+		sum, err := configChecksum(obj.UnstructuredObject())
+		if err != nil {
+			return nil, err
+		}
+		checksums[objectRef{obj.Kind, namespaceOf(obj), obj.Name}] = sum
+	}
+
+	out := make(object.K8sObjects, 0, len(objs))
+	for _, obj := range objs {
+		u := obj.UnstructuredObject()
+		if podTemplate(u) == nil {
+			out = append(out, obj)
+			continue
+		}
+		annotations := make(map[string]string)
+		for _, ref := range configRefs(podSpec(u)) {
+			ref.namespace = namespaceOf(obj)
+			if sum, ok := checksums[ref]; ok {
+				annotations[checksumAnnotation(ref.kind, ref.name)] = sum
+			}
+		}
+		if len(annotations) == 0 {
+			out = append(out, obj)
+			continue
+		}
+		c := u.DeepCopy()
+		if err := addNested(podTemplate(c), annotations, "metadata", "annotations"); err != nil {
+			return nil, err
+		}
+		out = append(out, update(c))
+	}
+	return out, nil
+}
+
+// configChecksum returns the checksum of the data of a ConfigMap or Secret.
+func configChecksum(u *unstructured.Unstructured) (string, error) {
+	data := make(map[string]any)
+	for _, key := range []string{"data", "binaryData", "stringData"} {
+		if v, ok := u.Object[key]; ok {
+			data[key] = v
+		}
+	}
+	// Maps are marshaled with sorted keys, so equal data has equal checksums.
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// checksumAnnotation returns the key of the annotation for the checksum of the ConfigMap or Secret of kind named name.
+// The name part of annotation keys has at most 63 characters, so longer keys are shortened and end with a hash of the
+// name instead, which keeps the keys of different names apart.
+func checksumAnnotation(kind, name string) string {
+	key := strings.ToLower(kind) + "-" + name
+	if len(key) > annotationNameMaxLength {
+		sum := sha256.Sum256([]byte(name))
+		hash := hex.EncodeToString(sum[:])[:8]
+		key = key[:annotationNameMaxLength-len(hash)-1] + "-" + hash
+	}
+	return ChecksumAnnotationPrefix + key
+}
+
+// configRefs returns the ConfigMaps and Secrets the pod spec references, without their namespace.
+func configRefs(spec map[string]any) []objectRef {
+	var refs []objectRef
+	add := func(kind, name string) {
+		if name != "" {
+			refs = append(refs, objectRef{kind: kind, name: name})
+		}
+	}
+	for _, v := range maps(spec["volumes"]) {
+		add(names.ConfigMapStr, stringAt(v, "configMap", "name"))
+		add(names.SecretStr, stringAt(v, "secret", "secretName"))
+		projected, _, _ := unstructured.NestedSlice(v, "projected", "sources")
+		for _, s := range maps(projected) {
+			add(names.ConfigMapStr, stringAt(s, "configMap", "name"))
+			add(names.SecretStr, stringAt(s, "secret", "name"))
+		}
+	}
+	for _, c := range containers(spec) {
+		for _, e := range maps(c["envFrom"]) {
+			add(names.ConfigMapStr, stringAt(e, "configMapRef", "name"))
+			add(names.SecretStr, stringAt(e, "secretRef", "name"))
+		}
+		for _, e := range maps(c["env"]) {
+			add(names.ConfigMapStr, stringAt(e, "valueFrom", "configMapKeyRef", "name"))
+			add(names.SecretStr, stringAt(e, "valueFrom", "secretKeyRef", "name"))
+		}
+	}
+	return refs
+}
@@ -0,0 +1,210 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const checksumManifest = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: ns
+data:
+  key: %s
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: creds
+  namespace: ns
+stringData:
+  password: hunter2
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: other-namespace
+  namespace: other
+data:
+  key: value
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: ns
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        envFrom:
+        - configMapRef:
+            name: config
+      containers:
+      - name: app
+        env:
+        - name: PASSWORD
+          valueFrom:
+            secretKeyRef:
+              name: creds
+              key: password
+      volumes:
+      - name: config
+        configMap:
+          name: config
+      - name: other
+        configMap:
+          name: other-namespace
+      - name: external
+        secret:
+          secretName: external
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: job
+  namespace: ns
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          volumes:
+          - name: projected
+            projected:
+              sources:
+              - secret:
+                  name: creds
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+  namespace: ns
+`
+
+func checksumAnnotations(t *testing.T, data string) map[string]map[string]string {
+	manifest := fmt.Sprintf(checksumManifest, data)
+	out, err := Manifest(manifest, ConfigChecksums("ns"))
+	require.NoError(t, err)
+	objs, err := object.ParseK8sObjectsFromYAMLManifest(out)
+	require.NoError(t, err)
+	require.Len(t, objs, 6)
+	got := make(map[string]map[string]string)
+	for _, obj := range objs {
+		if tmpl := podTemplate(obj.UnstructuredObject()); tmpl != nil {
+			got[obj.Kind], _, _ = unstructured.NestedStringMap(tmpl, "metadata", "annotations")
+		}
+	}
+	return got
+}
+
+func TestConfigChecksums(t *testing.T) {
+	got := checksumAnnotations(t, "value")
+	assert.Equal(t, map[string]map[string]string{
+		"Deployment": {
+			"checksum/configmap-config": "6d03299fbba4e28d5b65007acecd643423db710bfdde6c6750d8dbf28f80fc8c",
+			"checksum/secret-creds":     "b5a30d1df892d1c6ee4b4d70ed0d62a68159c4e63e20b06a38bf65dbf25d735c",
+		},
+		"CronJob": {
+			"checksum/secret-creds": "b5a30d1df892d1c6ee4b4d70ed0d62a68159c4e63e20b06a38bf65dbf25d735c",
+		},
+	}, got)
+
+	changed := checksumAnnotations(t, "changed")
+	assert.NotEqual(t, got["Deployment"]["checksum/configmap-config"], changed["Deployment"]["checksum/configmap-config"])
+	assert.Equal(t, got["Deployment"]["checksum/secret-creds"], changed["Deployment"]["checksum/secret-creds"])
+	assert.Equal(t, got["CronJob"], changed["CronJob"])
+}
+
+func TestConfigChecksumsDefaultNamespace(t *testing.T) {
+	manifest := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: explicit
+  namespace: ns
+data:
+  key: value
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: implicit
+data:
+  key: value
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: foreign
+  namespace: other
+data:
+  key: value
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: implicit
+spec:
+  template:
+    spec:
+      volumes:
+      - configMap:
+          name: explicit
+      - configMap:
+          name: implicit
+      - configMap:
+          name: foreign
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: explicit
+  namespace: ns
+spec:
+  template:
+    spec:
+      volumes:
+      - configMap:
+          name: explicit
+      - configMap:
+          name: implicit
+      - configMap:
+          name: foreign
+`
+	out, err := Manifest(manifest, ConfigChecksums("ns"))
+	require.NoError(t, err)
+	objs, err := object.ParseK8sObjectsFromYAMLManifest(out)
+	require.NoError(t, err)
+	sum := "6d03299fbba4e28d5b65007acecd643423db710bfdde6c6750d8dbf28f80fc8c"
+	got := make(map[string]map[string]string)
+	for _, obj := range objs {
+		if tmpl := podTemplate(obj.UnstructuredObject()); tmpl != nil {
+			got[obj.Name], _, _ = unstructured.NestedStringMap(tmpl, "metadata", "annotations")
+		}
+	}
+	want := map[string]string{"checksum/configmap-explicit": sum, "checksum/configmap-implicit": sum}
+	assert.Equal(t, map[string]map[string]string{"implicit": want, "explicit": want}, got)
+}
+
+func TestChecksumAnnotation(t *testing.T) {
+	assert.Equal(t, "checksum/configmap-config", checksumAnnotation("ConfigMap", "config"))
+	assert.Equal(t, "checksum/secret-config", checksumAnnotation("Secret", "config"))
+
+	long := strings.Repeat("a", 253)
+	key := checksumAnnotation("ConfigMap", long)
+	assert.Empty(t, validation.IsQualifiedName(key))
+	assert.NotEqual(t, key, checksumAnnotation("ConfigMap", long[:252]+"b"))
+	assert.Empty(t, validation.IsQualifiedName(checksumAnnotation("Secret", strings.Repeat("a", 56))))
+}
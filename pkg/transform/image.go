@@ -0,0 +1,184 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+)
+
+const (
+	// defaultRegistry is the registry of images whose references do not name one.
+	defaultRegistry = "docker.io"
+	// officialRepositoryPrefix is the namespace of the repositories of defaultRegistry that are named without one.
+	officialRepositoryPrefix = "library/"
+)
+
+// ImageRule rewrites the container images it matches. A rule matches the images of Registry and Repository, or all
+// images if neither is set. At least one of NewRegistry, NewRepository, NewTag and Digest must be set.
+type ImageRule struct {
+	// Registry matches images of the registry, as in quay.io or localhost:5000. Images whose reference does not name a
+	// registry are from docker.io.
+	Registry string `json:"registry,omitempty"`
+	// Repository matches images of the repository, the reference without the registry, tag and digest, as in
+	// stackrox/main. Repositories of docker.io named without a namespace are in library, so nginx and library/nginx
+	// match the same images.
+	Repository string `json:"repository,omitempty"`
+	// NewRegistry replaces the registry of matching images. Repositories of docker.io named without a namespace are
+	// qualified with library, so nginx becomes <NewRegistry>/library/nginx.
+	NewRegistry string `json:"newRegistry,omitempty"`
+	// NewRepository replaces the repository of matching images.
+	NewRepository string `json:"newRepository,omitempty"`
+	// NewTag replaces the tag of matching images. Unless Digest is set as well, their digest is removed.
+	NewTag string `json:"newTag,omitempty"`
+	// Digest replaces the digest of matching images, as in sha256:<hex>.
+	Digest string `json:"digest,omitempty"`
+}
+
+// Images returns a Transformer that rewrites the images of the containers, init containers and ephemeral containers
+// of Pods and of the pod templates of Deployments, StatefulSets, DaemonSets, ReplicaSets, Jobs and CronJobs with the
+// first of rules that matches each image.
+func Images(rules ...ImageRule) Transformer {
+	return TransformerFunc(func(objs object.K8sObjects) (object.K8sObjects, error) {
+		return rewriteImages(objs, rules)
+	})
+}
+
+func rewriteImages(objs object.K8sObjects, rules []ImageRule) (object.K8sObjects, error) {
+	for i, r := range rules {
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("image rule %d: %v", i, err)
+		}
+	}
+	out := make(object.K8sObjects, 0, len(objs))
+	for _, obj := range objs {
+		if podSpec(obj.UnstructuredObject()) == nil {
+			out = append(out, obj)
+			continue
+		}
+		c := obj.UnstructuredObject().DeepCopy()
+		changed := false
+		for _, container := range containers(podSpec(c)) {
+			image := stringAt(container, "image")
+			if image == "" {
+				continue
+			}
+			if rewritten := rewriteImage(image, rules); rewritten != image {
+				container["image"] = rewritten
+				changed = true
+			}
+		}
+		if !changed {
+			out = append(out, obj)
+			continue
+		}
+		out = append(out, update(c))
+	}
+	return out, nil
+}
+
+func (r ImageRule) validate() error {
+	if r.NewRegistry == "" && r.NewRepository == "" && r.NewTag == "" && r.Digest == "" {
+		return fmt.Errorf("must set newRegistry, newRepository, newTag or digest")
+	}
+	if r.Digest != "" && !strings.Contains(r.Digest, ":") {
+		return fmt.Errorf("digest %q must have the form <algorithm>:<hex>", r.Digest)
+	}
+	return nil
+}
+
+// rewriteImage returns image rewritten with the first of rules that matches it, or image if none does.
+func rewriteImage(image string, rules []ImageRule) string {
+	ref := parseImage(image)
+	for _, r := range rules {
+		if !r.matches(ref) {
+			continue
+		}
+		if r.NewRegistry != "" {
+			ref.repository = ref.normalizedRepository()
+			ref.registry = r.NewRegistry
+		}
+		if r.NewRepository != "" {
+			ref.repository = r.NewRepository
+		}
+		if r.NewTag != "" {
+			ref.tag = r.NewTag
+			ref.digest = ""
+		}
+		if r.Digest != "" {
+			ref.digest = r.Digest
+		}
+		return ref.String()
+	}
+	return image
+}
+
+func (r ImageRule) matches(ref imageRef) bool {
+	if r.Registry != "" && r.Registry != ref.normalizedRegistry() {
+		return false
+	}
+	if r.Repository == "" || r.Repository == ref.repository {
+		return true
+	}
+	return ref.normalizedRegistry() == defaultRegistry &&
+		imageRef{repository: r.Repository}.normalizedRepository() == ref.normalizedRepository()
+}
+
+// imageRef is a container image reference, split into its parts.
+type imageRef struct {
+	// registry is empty if the reference does not name a registry.
+	registry, repository, tag, digest string
+}
+
+// normalizedRegistry returns the registry of r, defaultRegistry if the reference does not name one.
+func (r imageRef) normalizedRegistry() string {
+	if r.registry == "" {
+		return defaultRegistry
+	}
+	return r.registry
+}
+
+// normalizedRepository returns the repository of r, qualified with officialRepositoryPrefix if r is an image of
+// defaultRegistry named without a namespace.
+func (r imageRef) normalizedRepository() string {
+	if r.normalizedRegistry() == defaultRegistry && !strings.Contains(r.repository, "/") {
+		return officialRepositoryPrefix + r.repository
+	}
+	return r.repository
+}
+
+// parseImage splits the image reference image into its parts. The first component of the reference is the registry
+// if it contains a '.' or ':', or is localhost, as container runtimes resolve it.
+func parseImage(image string) imageRef {
+	var ref imageRef
+	rest := image
+	if i := strings.Index(rest, "@"); i >= 0 {
+		rest, ref.digest = rest[:i], rest[i+1:]
+	}
+	if i := strings.LastIndex(rest, ":"); i >= 0 && !strings.Contains(rest[i:], "/") {
+		rest, ref.tag = rest[:i], rest[i+1:]
+	}
+	if i := strings.Index(rest, "/"); i >= 0 {
+		if first := rest[:i]; strings.ContainsAny(first, ".:") || first == "localhost" {
+			ref.registry, rest = first, rest[i+1:]
+		}
+	}
+	ref.repository = rest
+	return ref
+}
+
+// String returns the image reference.
+func (r imageRef) String() string {
+	var sb strings.Builder
+	if r.registry != "" {
+		sb.WriteString(r.registry + "/")
+	}
+	sb.WriteString(r.repository)
+	if r.tag != "" {
+		sb.WriteString(":" + r.tag)
+	}
+	if r.digest != "" {
+		sb.WriteString("@" + r.digest)
+	}
+	return sb.String()
+}
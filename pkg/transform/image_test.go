@@ -0,0 +1,188 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewriteImage(t *testing.T) {
+	tests := []struct {
+		desc  string
+		image string
+		rules []ImageRule
+		want  string
+	}{
+		{
+			desc:  "registry of image without registry",
+			image: "nginx:1.25",
+			rules: []ImageRule{{Registry: "docker.io", NewRegistry: "mirror.example.com"}},
+			want:  "mirror.example.com/library/nginx:1.25",
+		},
+		{
+			desc:  "registry of official image",
+			image: "docker.io/nginx",
+			rules: []ImageRule{{Registry: "docker.io", NewRegistry: "mirror.example.com/docker"}},
+			want:  "mirror.example.com/docker/library/nginx",
+		},
+		{
+			desc:  "registry of image with namespace",
+			image: "stackrox/main:4.0",
+			rules: []ImageRule{{Registry: "docker.io", NewRegistry: "mirror.example.com"}},
+			want:  "mirror.example.com/stackrox/main:4.0",
+		},
+		{
+			desc:  "official repository matches qualified repository",
+			image: "nginx:1.25",
+			rules: []ImageRule{{Repository: "library/nginx", NewTag: "1.26"}},
+			want:  "nginx:1.26",
+		},
+		{
+			desc:  "qualified repository matches official repository",
+			image: "docker.io/library/nginx:1.25",
+			rules: []ImageRule{{Repository: "nginx", NewTag: "1.26"}},
+			want:  "docker.io/library/nginx:1.26",
+		},
+		{
+			desc:  "official repository only on docker.io",
+			image: "quay.io/nginx:1.25",
+			rules: []ImageRule{{Repository: "library/nginx", NewTag: "1.26"}},
+			want:  "quay.io/nginx:1.25",
+		},
+		{
+			desc:  "new repository replaces official repository",
+			image: "nginx:1.25",
+			rules: []ImageRule{{Repository: "nginx", NewRegistry: "quay.io", NewRepository: "mirror/nginx"}},
+			want:  "quay.io/mirror/nginx:1.25",
+		},
+		{
+			desc:  "registry with port",
+			image: "localhost:5000/app/web@sha256:abc",
+			rules: []ImageRule{{Registry: "localhost:5000", NewRegistry: "mirror.example.com/cache"}},
+			want:  "mirror.example.com/cache/app/web@sha256:abc",
+		},
+		{
+			desc:  "other registry",
+			image: "quay.io/stackrox/main:4.0",
+			rules: []ImageRule{{Registry: "docker.io", NewRegistry: "mirror.example.com"}},
+			want:  "quay.io/stackrox/main:4.0",
+		},
+		{
+			desc:  "repository",
+			image: "quay.io/stackrox/main:4.0",
+			rules: []ImageRule{{Repository: "stackrox/main", NewRepository: "rhacs/main"}},
+			want:  "quay.io/rhacs/main:4.0",
+		},
+		{
+			desc:  "new tag removes digest",
+			image: "quay.io/stackrox/main:4.0@sha256:abc",
+			rules: []ImageRule{{Repository: "stackrox/main", NewTag: "4.1"}},
+			want:  "quay.io/stackrox/main:4.1",
+		},
+		{
+			desc:  "digest keeps tag",
+			image: "quay.io/stackrox/main:4.0",
+			rules: []ImageRule{{Repository: "stackrox/main", Digest: "sha256:def"}},
+			want:  "quay.io/stackrox/main:4.0@sha256:def",
+		},
+		{
+			desc:  "first matching rule",
+			image: "quay.io/stackrox/main:4.0",
+			rules: []ImageRule{
+				{Repository: "stackrox/other", NewTag: "other"},
+				{Registry: "quay.io", NewRegistry: "mirror.example.com"},
+				{NewTag: "ignored"},
+			},
+			want: "mirror.example.com/stackrox/main:4.0",
+		},
+		{
+			desc:  "no match",
+			image: "busybox",
+			rules: []ImageRule{{Repository: "nginx", NewTag: "latest"}},
+			want:  "busybox",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			assert.Equal(t, tt.want, rewriteImage(tt.image, tt.rules))
+		})
+	}
+}
+
+func TestImages(t *testing.T) {
+	manifest := `
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: job
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          initContainers:
+          - name: init
+            image: busybox:1.36
+          containers:
+          - name: job
+            image: quay.io/stackrox/main:4.0
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: pod
+spec:
+  containers:
+  - name: app
+    image: nginx
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  image: nginx
+`
+	want := `
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: job
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          initContainers:
+          - name: init
+            image: mirror.example.com/library/busybox:1.36
+          containers:
+          - name: job
+            image: mirror.example.com/stackrox/main:4.0
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: pod
+spec:
+  containers:
+  - name: app
+    image: mirror.example.com/library/nginx
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  image: nginx
+`
+	got, err := Manifest(manifest, Images(ImageRule{NewRegistry: "mirror.example.com"}))
+	require.NoError(t, err)
+	assertManifestEqual(t, want, got)
+
+	_, err = Manifest(manifest, Images(ImageRule{Registry: "docker.io"}))
+	assert.EqualError(t, err, "image rule 0: must set newRegistry, newRepository, newTag or digest")
+	_, err = Manifest(manifest, Images(ImageRule{Digest: "abc"}))
+	assert.EqualError(t, err, `image rule 0: digest "abc" must have the form <algorithm>:<hex>`)
+}
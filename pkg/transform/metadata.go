@@ -0,0 +1,122 @@
+package transform
+
+import (
+	"fmt"
+
+	names "github.com/stackrox/k8s-overlay-patch/pkg/name"
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"github.com/stackrox/k8s-overlay-patch/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// labelSelectorPaths are the paths of the label selectors that ExtendSelectors extends, by kind. Services have a map of
+// labels, all other kinds a label selector with matchLabels.
+var labelSelectorPaths = map[string][]string{
+	names.DeploymentStr:  {"spec", "selector", "matchLabels"},
+	names.StatefulSetStr: {"spec", "selector", "matchLabels"},
+	names.DaemonSetStr:   {"spec", "selector", "matchLabels"},
+	names.ReplicaSetStr:  {"spec", "selector", "matchLabels"},
+	names.PDBStr:         {"spec", "selector", "matchLabels"},
+	names.ServiceStr:     {"spec", "selector"},
+}
+
+// CommonMetadata are the labels and annotations Metadata adds to the objects of a manifest.
+type CommonMetadata struct {
+	// Labels are added to the selected objects and to the pod templates of selected workloads, replacing labels with
+	// the same keys.
+	Labels map[string]string
+	// Annotations are added to the selected objects and to the pod templates of selected workloads, replacing
+	// annotations with the same keys.
+	Annotations map[string]string
+	// Kinds are the kinds of the selected objects. Objects of all kinds are selected if it is empty.
+	Kinds []string
+	// Selector selects objects by the labels they have before Labels are added. All objects are selected if it is nil.
+	Selector *metav1.LabelSelector
+	// ExtendSelectors are the objects, by kind and name, whose selectors Labels are added to as well, if they are
+	// selected Deployments, StatefulSets, DaemonSets, ReplicaSets, PodDisruptionBudgets or Services with a selector.
+	// The selectors of workloads are immutable, so only objects that do not exist in the cluster yet should be listed,
+	// such as objects added with the add section of a patch file.
+	ExtendSelectors []types.K8sObjectReference
+}
+
+// Metadata returns a Transformer that adds the labels and annotations of m to the objects it selects.
+func Metadata(m CommonMetadata) Transformer {
+	return TransformerFunc(m.transform)
+}
+
+func (m CommonMetadata) transform(objs object.K8sObjects) (object.K8sObjects, error) {
+	selector := labels.Everything()
+	if m.Selector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(m.Selector); err != nil {
+			return nil, fmt.Errorf("invalid selector: %v", err)
+		}
+	}
+	kinds := make(map[string]bool, len(m.Kinds))
+	for _, k := range m.Kinds {
+		kinds[k] = true
+	}
+	extend := make(map[types.K8sObjectReference]bool, len(m.ExtendSelectors))
+	for _, ref := range m.ExtendSelectors {
+		extend[ref] = true
+	}
+
+	out := make(object.K8sObjects, 0, len(objs))
+	for _, obj := range objs {
+		u := obj.UnstructuredObject()
+		if (len(kinds) > 0 && !kinds[obj.Kind]) || !selector.Matches(labels.Set(u.GetLabels())) {
+			out = append(out, obj)
+			continue
+		}
+		c := u.DeepCopy()
+		c.SetLabels(merge(c.GetLabels(), m.Labels))
+		c.SetAnnotations(merge(c.GetAnnotations(), m.Annotations))
+		if t := podTemplate(c); t != nil {
+			if err := addNested(t, m.Labels, "metadata", "labels"); err != nil {
+				return nil, err
+			}
+			if err := addNested(t, m.Annotations, "metadata", "annotations"); err != nil {
+				return nil, err
+			}
+		}
+		if path, ok := labelSelectorPaths[obj.Kind]; ok && extend[types.K8sObjectReference{Kind: obj.Kind, Name: obj.Name}] {
+			// Missing and empty selectors select all pods or none, which adding labels would change.
+			if sel := mapAt(c.Object, "spec", "selector"); len(sel) > 0 {
+				if err := addNested(c.Object, m.Labels, path...); err != nil {
+					return nil, err
+				}
+			}
+		}
+		out = append(out, update(c))
+	}
+	return out, nil
+}
+
+// merge returns a map with the entries of m and add, where those of add take precedence, or m if add is empty.
+func merge(m, add map[string]string) map[string]string {
+	if len(add) == 0 {
+		return m
+	}
+	out := make(map[string]string, len(m)+len(add))
+	for k, v := range m {
+		out[k] = v
+	}
+	for k, v := range add {
+		out[k] = v
+	}
+	return out
+}
+
+// addNested adds the entries of add to the map of strings at path in m, which is created if it does not exist.
+func addNested(m map[string]any, add map[string]string, path ...string) error {
+	if len(add) == 0 {
+		return nil
+	}
+	existing, _, err := unstructured.NestedStringMap(m, path...)
+	if err != nil {
+		return err
+	}
+	return unstructured.SetNestedStringMap(m, merge(existing, add), path...)
+}
@@ -0,0 +1,191 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"github.com/stackrox/k8s-overlay-patch/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const metadataManifest = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: new
+  labels:
+    app: new
+spec:
+  selector:
+    matchLabels:
+      app: new
+  template:
+    metadata:
+      labels:
+        app: new
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: existing
+  labels:
+    app: existing
+spec:
+  selector:
+    matchLabels:
+      app: existing
+  template:
+    metadata:
+      labels:
+        app: existing
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: headless
+  labels:
+    app: new
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  labels:
+    owner: someone
+  annotations:
+    note: keep
+`
+
+func TestMetadata(t *testing.T) {
+	got, err := Manifest(metadataManifest, Metadata(CommonMetadata{
+		Labels:          map[string]string{"owner": "team", "cost-center": "42"},
+		Annotations:     map[string]string{"contact": "team@example.com"},
+		ExtendSelectors: []types.K8sObjectReference{{Kind: "Deployment", Name: "new"}, {Kind: "Service", Name: "headless"}},
+	}))
+	require.NoError(t, err)
+	want := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: new
+  labels:
+    app: new
+    owner: team
+    cost-center: "42"
+  annotations:
+    contact: team@example.com
+spec:
+  selector:
+    matchLabels:
+      app: new
+      owner: team
+      cost-center: "42"
+  template:
+    metadata:
+      labels:
+        app: new
+        owner: team
+        cost-center: "42"
+      annotations:
+        contact: team@example.com
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: existing
+  labels:
+    app: existing
+    owner: team
+    cost-center: "42"
+  annotations:
+    contact: team@example.com
+spec:
+  selector:
+    matchLabels:
+      app: existing
+  template:
+    metadata:
+      labels:
+        app: existing
+        owner: team
+        cost-center: "42"
+      annotations:
+        contact: team@example.com
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: headless
+  labels:
+    app: new
+    owner: team
+    cost-center: "42"
+  annotations:
+    contact: team@example.com
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  labels:
+    owner: team
+    cost-center: "42"
+  annotations:
+    note: keep
+    contact: team@example.com
+`
+	assertManifestEqual(t, want, got)
+}
+
+func TestMetadataSelection(t *testing.T) {
+	m := CommonMetadata{Labels: map[string]string{"owner": "team"}}
+	tests := []struct {
+		desc     string
+		kinds    []string
+		selector *metav1.LabelSelector
+		want     []string
+	}{
+		{
+			desc: "all objects",
+			want: []string{"new", "existing", "headless", "config"},
+		},
+		{
+			desc:  "kinds",
+			kinds: []string{"Deployment"},
+			want:  []string{"new", "existing"},
+		},
+		{
+			desc:     "selector",
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "new"}},
+			want:     []string{"new", "headless"},
+		},
+		{
+			desc:     "kinds and selector",
+			kinds:    []string{"Service"},
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "new"}},
+			want:     []string{"headless"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			m.Kinds, m.Selector = tt.kinds, tt.selector
+			out, err := Manifest(metadataManifest, Metadata(m))
+			require.NoError(t, err)
+			objs, err := object.ParseK8sObjectsFromYAMLManifest(out)
+			require.NoError(t, err)
+			var got []string
+			for _, obj := range objs {
+				if obj.UnstructuredObject().GetLabels()["owner"] == "team" {
+					got = append(got, obj.Name)
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	m.Kinds, m.Selector = nil, &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "a", Operator: "Bogus"}}}
+	_, err := Manifest(metadataManifest, Metadata(m))
+	assert.ErrorContains(t, err, "invalid selector")
+}
@@ -0,0 +1,129 @@
+package transform
+
+import (
+	names "github.com/stackrox/k8s-overlay-patch/pkg/name"
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// clusterScopedKinds are the built-in kinds that are not namespaced.
+var clusterScopedKinds = map[string]bool{
+	names.NamespaceStr:                        true,
+	names.ClusterRoleStr:                      true,
+	names.ClusterRoleBindingStr:               true,
+	names.CRDStr:                              true,
+	names.APIServiceStr:                       true,
+	names.MutatingWebhookStr:                  true,
+	names.ValidatingWebhookStr:                true,
+	names.ValidatingAdmissionPolicyStr:        true,
+	names.ValidatingAdmissionPolicyBindingStr: true,
+	names.NodeStr:                             true,
+	names.PVStr:                               true,
+	names.StorageClassStr:                     true,
+	names.VolumeAttributesClassStr:            true,
+	names.CSIDriverStr:                        true,
+	names.CSINodeStr:                          true,
+	names.VolumeAttachmentStr:                 true,
+	names.PriorityClassStr:                    true,
+	names.RuntimeClassStr:                     true,
+	names.IngressClassStr:                     true,
+	names.PSPStr:                              true,
+	names.CSRStr:                              true,
+	names.ClusterTrustBundleStr:               true,
+	names.FlowSchemaStr:                       true,
+	names.PriorityLevelConfigurationStr:       true,
+	names.ServiceCIDRStr:                      true,
+	names.IPAddressStr:                        true,
+}
+
+// Namespace returns a Transformer that moves all namespaced objects to namespace, and rewrites the namespaces of the
+// references to them:
+//   - the ServiceAccount subjects of RoleBindings and ClusterRoleBindings;
+//   - the Services of webhook configurations, APIServices and CustomResourceDefinition conversion webhooks.
+//
+// Objects without a namespace are in defaultNamespace, the default namespace, as references to them name it. A
+// reference is rewritten if the manifest has the object it refers to in the namespace of the reference. Objects of the
+// built-in cluster scoped kinds and of the custom resources of cluster scoped CustomResourceDefinitions in the manifest
+// keep having no namespace. All other kinds are assumed to be namespaced.
+//
+// Namespace objects are cluster scoped, so they are left unchanged: a Namespace of the manifest is not renamed to
+// namespace, and namespace is not created. It must exist already, or be added to the manifest, e.g. with the add
+// section of a patch file.
+func Namespace(defaultNamespace, namespace string) Transformer {
+	return TransformerFunc(func(objs object.K8sObjects) (object.K8sObjects, error) {
+		return setNamespace(objs, defaultNamespace, namespace), nil
+	})
+}
+
+// namespaceSetter sets the namespace at path in m to the new namespace, if it refers to a moved object of kind named name.
+type namespaceSetter func(kind, name string, m map[string]any, path ...string)
+
+func setNamespace(objs object.K8sObjects, defaultNamespace, namespace string) object.K8sObjects {
+	clusterScoped := make(map[string]bool, len(clusterScopedKinds))
+	for kind := range clusterScopedKinds {
+		clusterScoped[kind] = true
+	}
+	for _, obj := range objs {
+		if obj.Kind == names.CRDStr && stringAt(obj.UnstructuredObject().Object, "spec", "scope") == "Cluster" {
+			clusterScoped[stringAt(obj.UnstructuredObject().Object, "spec", "names", "kind")] = true
+		}
+	}
+	moved := make(map[objectRef]bool)
+	for _, obj := range objs {
+		if clusterScoped[obj.Kind] {
+			continue
+		}
+		ns := obj.Namespace
+		if ns == "" {
+			ns = defaultNamespace
+		}
+		moved[objectRef{obj.Kind, ns, obj.Name}] = true
+	}
+	setRef := func(kind, name string, m map[string]any, path ...string) {
+		ns := stringAt(m, path...)
+		if ns == "" || !moved[objectRef{kind, ns, name}] {
+			return
+		}
+		// The field holds the namespace, so setting it cannot fail.
+		_ = unstructured.SetNestedField(m, namespace, path...)
+	}
+
+	out := make(object.K8sObjects, 0, len(objs))
+	for _, obj := range objs {
+		c := obj.UnstructuredObject().DeepCopy()
+		if !clusterScoped[obj.Kind] {
+			c.SetNamespace(namespace)
+		}
+		setNamespaceRefs(c, setRef)
+		out = append(out, update(c))
+	}
+	return out
+}
+
+// setNamespaceRefs sets the namespaces of the references of u to other objects with set.
+func setNamespaceRefs(u *unstructured.Unstructured, set namespaceSetter) {
+	switch u.GetKind() {
+	case names.RoleBindingStr, names.ClusterRoleBindingStr:
+		for _, s := range maps(u.Object["subjects"]) {
+			if stringAt(s, "kind") == names.ServiceAccountStr {
+				set(names.ServiceAccountStr, stringAt(s, "name"), s, "namespace")
+			}
+		}
+	case names.MutatingWebhookStr, names.ValidatingWebhookStr:
+		for _, w := range maps(u.Object["webhooks"]) {
+			setServiceRefNamespace(mapAt(w, "clientConfig", "service"), set)
+		}
+	case names.APIServiceStr:
+		setServiceRefNamespace(mapAt(u.Object, "spec", "service"), set)
+	case names.CRDStr:
+		setServiceRefNamespace(mapAt(u.Object, "spec", "conversion", "webhook", "clientConfig", "service"), set)
+	}
+}
+
+// setServiceRefNamespace sets the namespace of a service reference with a namespace and name, such as the service of
+// a webhook client config, with set.
+func setServiceRefNamespace(ref map[string]any, set namespaceSetter) {
+	if ref != nil {
+		set(names.ServiceStr, stringAt(ref, "name"), ref, "namespace")
+	}
+}
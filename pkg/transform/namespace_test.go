@@ -0,0 +1,236 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespace(t *testing.T) {
+	manifest := `
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: app
+  namespace: old
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: webhook
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: reader
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: reader
+subjects:
+- kind: ServiceAccount
+  name: app
+  namespace: old
+- kind: ServiceAccount
+  name: external
+  namespace: old
+- kind: ServiceAccount
+  name: app
+  namespace: kube-system
+---
+apiVersion: admissionregistration.k8s.io/v1
+kind: MutatingWebhookConfiguration
+metadata:
+  name: webhook
+webhooks:
+- name: webhook.example.com
+  clientConfig:
+    service:
+      name: webhook
+      namespace: old
+---
+apiVersion: apiregistration.k8s.io/v1
+kind: APIService
+metadata:
+  name: v1.example.com
+spec:
+  service:
+    name: webhook
+    namespace: default
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  scope: Cluster
+  names:
+    kind: Widget
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: widget
+---
+apiVersion: example.com/v1
+kind: Gadget
+metadata:
+  name: gadget
+  namespace: old
+`
+	want := `
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: app
+  namespace: new
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: webhook
+  namespace: new
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: reader
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: reader
+subjects:
+- kind: ServiceAccount
+  name: app
+  namespace: new
+- kind: ServiceAccount
+  name: external
+  namespace: old
+- kind: ServiceAccount
+  name: app
+  namespace: kube-system
+---
+apiVersion: admissionregistration.k8s.io/v1
+kind: MutatingWebhookConfiguration
+metadata:
+  name: webhook
+webhooks:
+- name: webhook.example.com
+  clientConfig:
+    service:
+      name: webhook
+      namespace: new
+---
+apiVersion: apiregistration.k8s.io/v1
+kind: APIService
+metadata:
+  name: v1.example.com
+spec:
+  service:
+    name: webhook
+    namespace: default
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  scope: Cluster
+  names:
+    kind: Widget
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: widget
+---
+apiVersion: example.com/v1
+kind: Gadget
+metadata:
+  name: gadget
+  namespace: new
+`
+	got, err := Manifest(manifest, Namespace("old", "new"))
+	require.NoError(t, err)
+	assertManifestEqual(t, want, got)
+}
+
+func TestNamespaceClusterScopedKinds(t *testing.T) {
+	manifest := `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: old
+---
+apiVersion: storage.k8s.io/v1beta1
+kind: VolumeAttributesClass
+metadata:
+  name: fast
+---
+apiVersion: certificates.k8s.io/v1alpha1
+kind: ClusterTrustBundle
+metadata:
+  name: bundle
+---
+apiVersion: networking.k8s.io/v1beta1
+kind: ServiceCIDR
+metadata:
+  name: cidr
+---
+apiVersion: networking.k8s.io/v1beta1
+kind: IPAddress
+metadata:
+  name: 10.0.0.1
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: old
+`
+	want := `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: old
+---
+apiVersion: storage.k8s.io/v1beta1
+kind: VolumeAttributesClass
+metadata:
+  name: fast
+---
+apiVersion: certificates.k8s.io/v1alpha1
+kind: ClusterTrustBundle
+metadata:
+  name: bundle
+---
+apiVersion: networking.k8s.io/v1beta1
+kind: ServiceCIDR
+metadata:
+  name: cidr
+---
+apiVersion: networking.k8s.io/v1beta1
+kind: IPAddress
+metadata:
+  name: 10.0.0.1
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: new
+`
+	got, err := Manifest(manifest, Namespace("old", "new"))
+	require.NoError(t, err)
+	assertManifestEqual(t, want, got)
+}
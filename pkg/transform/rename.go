@@ -0,0 +1,157 @@
+package transform
+
+import (
+	names "github.com/stackrox/k8s-overlay-patch/pkg/name"
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// fixedNameKinds are the kinds Rename does not rename. The names of CustomResourceDefinitions and APIServices are
+// determined by their group, and other objects refer to Namespaces by their names in metadata.namespace.
+var fixedNameKinds = map[string]bool{
+	names.NamespaceStr:  true,
+	names.CRDStr:        true,
+	names.APIServiceStr: true,
+}
+
+// Rename returns a Transformer that adds prefix and suffix to the names of all objects except Namespaces,
+// CustomResourceDefinitions and APIServices, and rewrites the references between objects of the manifest, so that
+// the renamed manifest stays consistent:
+//   - the ServiceAccount, ConfigMaps, Secrets and PersistentVolumeClaims of pod specs, through serviceAccountName,
+//     imagePullSecrets, volumes, envFrom and env valueFrom;
+//   - the governing Service of StatefulSets and the scale target of HorizontalPodAutoscalers;
+//   - the roleRef and ServiceAccount subjects of RoleBindings and ClusterRoleBindings;
+//   - the Services of webhook configurations, APIServices and Ingresses, and the TLS Secrets of Ingresses.
+//
+// Objects without a namespace are in namespace, the default namespace, as references to them name it. References to
+// objects that are not in the manifest are left alone, as are labels and selectors.
+func Rename(namespace, prefix, suffix string) Transformer {
+	return TransformerFunc(func(objs object.K8sObjects) (object.K8sObjects, error) {
+		return rename(objs, namespace, prefix, suffix), nil
+	})
+}
+
+// refRenamer renames the reference at path in m to an object of kind in namespace, if the object is renamed.
+type refRenamer func(kind, namespace string, m map[string]any, path ...string)
+
+func rename(objs object.K8sObjects, defaultNamespace, prefix, suffix string) object.K8sObjects {
+	// Cluster scoped objects and the references to them have no namespace either, so they are in the default
+	// namespace as well, where their kinds do not collide with those of namespaced objects.
+	ref := func(kind, namespace, name string) objectRef {
+		if namespace == "" {
+			namespace = defaultNamespace
+		}
+		return objectRef{kind, namespace, name}
+	}
+	renamed := make(map[objectRef]bool)
+	for _, obj := range objs {
+		if !fixedNameKinds[obj.Kind] {
+			renamed[ref(obj.Kind, obj.Namespace, obj.Name)] = true
+		}
+	}
+	renameRef := func(kind, namespace string, m map[string]any, path ...string) {
+		name := stringAt(m, path...)
+		if renamed[ref(kind, namespace, name)] {
+			// The field holds the name, so setting it cannot fail.
+			_ = unstructured.SetNestedField(m, prefix+name+suffix, path...)
+		}
+	}
+
+	out := make(object.K8sObjects, 0, len(objs))
+	for _, obj := range objs {
+		c := obj.UnstructuredObject().DeepCopy()
+		if renamed[ref(obj.Kind, obj.Namespace, obj.Name)] {
+			c.SetName(prefix + obj.Name + suffix)
+		}
+		renameRefs(c, renameRef)
+		out = append(out, update(c))
+	}
+	return out
+}
+
+// renameRefs renames the references of u to other objects with rename.
+func renameRefs(u *unstructured.Unstructured, rename refRenamer) {
+	ns := u.GetNamespace()
+	if spec := podSpec(u); spec != nil {
+		renamePodSpecRefs(spec, ns, rename)
+	}
+	switch u.GetKind() {
+	case names.StatefulSetStr:
+		rename(names.ServiceStr, ns, u.Object, "spec", "serviceName")
+	case names.HPAStr:
+		if target := mapAt(u.Object, "spec", "scaleTargetRef"); target != nil {
+			rename(stringAt(target, "kind"), ns, target, "name")
+		}
+	case names.RoleBindingStr, names.ClusterRoleBindingStr:
+		if roleRef := mapAt(u.Object, "roleRef"); roleRef != nil {
+			kind := stringAt(roleRef, "kind")
+			roleNs := ns
+			if kind == names.ClusterRoleStr {
+				roleNs = ""
+			}
+			rename(kind, roleNs, roleRef, "name")
+		}
+		for _, s := range maps(u.Object["subjects"]) {
+			if stringAt(s, "kind") != names.ServiceAccountStr {
+				continue
+			}
+			subjectNs := stringAt(s, "namespace")
+			if subjectNs == "" {
+				subjectNs = ns
+			}
+			rename(names.ServiceAccountStr, subjectNs, s, "name")
+		}
+	case names.MutatingWebhookStr, names.ValidatingWebhookStr:
+		for _, w := range maps(u.Object["webhooks"]) {
+			renameServiceRef(mapAt(w, "clientConfig", "service"), rename)
+		}
+	case names.APIServiceStr:
+		renameServiceRef(mapAt(u.Object, "spec", "service"), rename)
+	case names.IngressStr:
+		rename(names.ServiceStr, ns, u.Object, "spec", "defaultBackend", "service", "name")
+		for _, r := range maps(mapAt(u.Object, "spec")["rules"]) {
+			for _, p := range maps(mapAt(r, "http")["paths"]) {
+				rename(names.ServiceStr, ns, p, "backend", "service", "name")
+			}
+		}
+		for _, t := range maps(mapAt(u.Object, "spec")["tls"]) {
+			rename(names.SecretStr, ns, t, "secretName")
+		}
+	}
+}
+
+// renamePodSpecRefs renames the references of a pod spec in namespace ns with rename.
+func renamePodSpecRefs(spec map[string]any, ns string, rename refRenamer) {
+	rename(names.ServiceAccountStr, ns, spec, "serviceAccountName")
+	rename(names.ServiceAccountStr, ns, spec, "serviceAccount")
+	for _, s := range maps(spec["imagePullSecrets"]) {
+		rename(names.SecretStr, ns, s, "name")
+	}
+	for _, v := range maps(spec["volumes"]) {
+		rename(names.ConfigMapStr, ns, v, "configMap", "name")
+		rename(names.SecretStr, ns, v, "secret", "secretName")
+		rename(names.PVCStr, ns, v, "persistentVolumeClaim", "claimName")
+		for _, s := range maps(mapAt(v, "projected")["sources"]) {
+			rename(names.ConfigMapStr, ns, s, "configMap", "name")
+			rename(names.SecretStr, ns, s, "secret", "name")
+		}
+	}
+	for _, c := range containers(spec) {
+		for _, e := range maps(c["envFrom"]) {
+			rename(names.ConfigMapStr, ns, e, "configMapRef", "name")
+			rename(names.SecretStr, ns, e, "secretRef", "name")
+		}
+		for _, e := range maps(c["env"]) {
+			rename(names.ConfigMapStr, ns, e, "valueFrom", "configMapKeyRef", "name")
+			rename(names.SecretStr, ns, e, "valueFrom", "secretKeyRef", "name")
+		}
+	}
+}
+
+// renameServiceRef renames the Service of a service reference with a namespace and name, such as the service of a
+// webhook client config, with rename.
+func renameServiceRef(ref map[string]any, rename refRenamer) {
+	if ref != nil {
+		rename(names.ServiceStr, stringAt(ref, "namespace"), ref, "name")
+	}
+}
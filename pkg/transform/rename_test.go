@@ -0,0 +1,393 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRename(t *testing.T) {
+	manifest := `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: ns
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: app
+  namespace: ns
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: ns
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: creds
+  namespace: ns
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  namespace: ns
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+  namespace: ns
+spec:
+  selector:
+    app: app
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: app
+  namespace: ns
+spec:
+  serviceName: app
+  selector:
+    matchLabels:
+      app: app
+  template:
+    metadata:
+      labels:
+        app: app
+    spec:
+      serviceAccountName: app
+      imagePullSecrets:
+      - name: registry
+      containers:
+      - name: app
+        envFrom:
+        - configMapRef:
+            name: config
+        env:
+        - name: PASSWORD
+          valueFrom:
+            secretKeyRef:
+              name: creds
+              key: password
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: data
+      - name: projected
+        projected:
+          sources:
+          - configMap:
+              name: config
+          - secret:
+              name: external
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: reader
+  namespace: ns
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: reader
+subjects:
+- kind: ServiceAccount
+  name: app
+  namespace: ns
+- kind: ServiceAccount
+  name: app
+  namespace: other
+- kind: User
+  name: app
+---
+apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: webhook
+webhooks:
+- name: app.example.com
+  clientConfig:
+    service:
+      name: app
+      namespace: ns
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: app
+  namespace: ns
+spec:
+  tls:
+  - secretName: creds
+  rules:
+  - http:
+      paths:
+      - path: /
+        pathType: Prefix
+        backend:
+          service:
+            name: app
+            port:
+              number: 80
+`
+	want := `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: ns
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: blue-app-x
+  namespace: ns
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: blue-config-x
+  namespace: ns
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: blue-creds-x
+  namespace: ns
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: blue-data-x
+  namespace: ns
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: blue-app-x
+  namespace: ns
+spec:
+  selector:
+    app: app
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: blue-app-x
+  namespace: ns
+spec:
+  serviceName: blue-app-x
+  selector:
+    matchLabels:
+      app: app
+  template:
+    metadata:
+      labels:
+        app: app
+    spec:
+      serviceAccountName: blue-app-x
+      imagePullSecrets:
+      - name: registry
+      containers:
+      - name: app
+        envFrom:
+        - configMapRef:
+            name: blue-config-x
+        env:
+        - name: PASSWORD
+          valueFrom:
+            secretKeyRef:
+              name: blue-creds-x
+              key: password
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: blue-data-x
+      - name: projected
+        projected:
+          sources:
+          - configMap:
+              name: blue-config-x
+          - secret:
+              name: external
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: blue-reader-x
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: blue-reader-x
+  namespace: ns
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: blue-reader-x
+subjects:
+- kind: ServiceAccount
+  name: blue-app-x
+  namespace: ns
+- kind: ServiceAccount
+  name: app
+  namespace: other
+- kind: User
+  name: app
+---
+apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: blue-webhook-x
+webhooks:
+- name: app.example.com
+  clientConfig:
+    service:
+      name: blue-app-x
+      namespace: ns
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: blue-app-x
+  namespace: ns
+spec:
+  tls:
+  - secretName: blue-creds-x
+  rules:
+  - http:
+      paths:
+      - path: /
+        pathType: Prefix
+        backend:
+          service:
+            name: blue-app-x
+            port:
+              number: 80
+`
+	got, err := Manifest(manifest, Rename("ns", "blue-", "-x"))
+	require.NoError(t, err)
+	assertManifestEqual(t, want, got)
+}
+
+func TestRenameDefaultNamespace(t *testing.T) {
+	manifest := `
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: app
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: webhook
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+---
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: app
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: app
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: app
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: external
+subjects:
+- kind: ServiceAccount
+  name: app
+  namespace: stackrox
+- kind: ServiceAccount
+  name: app
+  namespace: other
+---
+apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: webhook
+webhooks:
+- name: webhook.example.com
+  clientConfig:
+    service:
+      name: webhook
+      namespace: stackrox
+`
+	want := `
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: x-app
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: x-webhook
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: x-app
+---
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: x-app
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: x-app
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: x-app
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: external
+subjects:
+- kind: ServiceAccount
+  name: x-app
+  namespace: stackrox
+- kind: ServiceAccount
+  name: app
+  namespace: other
+---
+apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: x-webhook
+webhooks:
+- name: webhook.example.com
+  clientConfig:
+    service:
+      name: x-webhook
+      namespace: stackrox
+`
+	got, err := Manifest(manifest, Rename("stackrox", "x-", ""))
+	require.NoError(t, err)
+	assertManifestEqual(t, want, got)
+}
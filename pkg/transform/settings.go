@@ -0,0 +1,70 @@
+package transform
+
+import (
+	"sort"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/types"
+)
+
+// Settings configure the transformers the command and the KRM function apply after the overlays, as their config
+// files hold them.
+type Settings struct {
+	// SetNamespace is the namespace all namespaced objects are moved to, see Namespace.
+	SetNamespace string `json:"setNamespace,omitempty"`
+	// NamePrefix is added to the names of all objects and the references between them, see Rename.
+	NamePrefix string `json:"namePrefix,omitempty"`
+	// NameSuffix is appended to the names of all objects and the references between them, see Rename.
+	NameSuffix string `json:"nameSuffix,omitempty"`
+	// CommonLabels are added to all objects and the pod templates of workloads, see Metadata.
+	CommonLabels map[string]string `json:"commonLabels,omitempty"`
+	// CommonAnnotations are added to all objects and the pod templates of workloads, see Metadata.
+	CommonAnnotations map[string]string `json:"commonAnnotations,omitempty"`
+	// ExtendSelectors are the objects whose selectors CommonLabels are added to as well, which should only be objects
+	// that do not exist in the cluster yet.
+	ExtendSelectors []types.K8sObjectReference `json:"extendSelectors,omitempty"`
+	// Images are the rules the images of containers are rewritten with, see Images.
+	Images []ImageRule `json:"images,omitempty"`
+	// ImageRegistries are the registries to replace in images, by the registry to replace them with. They apply to
+	// images no rule of Images matches.
+	ImageRegistries map[string]string `json:"imageRegistries,omitempty"`
+	// ConfigChecksums annotates pod templates with checksums of the ConfigMaps and Secrets they use, see
+	// ConfigChecksums.
+	ConfigChecksums bool `json:"configChecksums,omitempty"`
+}
+
+// Transformers returns the transformers s configures, in the order they are to be applied. namespace is the namespace
+// of the objects of the manifest that do not set one.
+func (s *Settings) Transformers(namespace string) []Transformer {
+	var out []Transformer
+	if s.SetNamespace != "" {
+		out = append(out, Namespace(namespace, s.SetNamespace))
+	}
+	if s.NamePrefix != "" || s.NameSuffix != "" {
+		out = append(out, Rename(namespace, s.NamePrefix, s.NameSuffix))
+	}
+	if len(s.CommonLabels) > 0 || len(s.CommonAnnotations) > 0 {
+		out = append(out, Metadata(CommonMetadata{
+			Labels:          s.CommonLabels,
+			Annotations:     s.CommonAnnotations,
+			ExtendSelectors: s.ExtendSelectors,
+		}))
+	}
+	if rules := append(append([]ImageRule{}, s.Images...), registryRules(s.ImageRegistries)...); len(rules) > 0 {
+		out = append(out, Images(rules...))
+	}
+	if s.ConfigChecksums {
+		out = append(out, ConfigChecksums(namespace))
+	}
+	return out
+}
+
+// registryRules returns the image rules that replace the registries that are the keys of registries with their values,
+// in the order of the old registries.
+func registryRules(registries map[string]string) []ImageRule {
+	rules := make([]ImageRule, 0, len(registries))
+	for old, registry := range registries {
+		rules = append(rules, ImageRule{Registry: old, NewRegistry: registry})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Registry < rules[j].Registry })
+	return rules
+}
@@ -0,0 +1,62 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettingsTransformers(t *testing.T) {
+	assert.Empty(t, (&Settings{}).Transformers("default"))
+
+	s := &Settings{
+		SetNamespace:    "prod",
+		NamePrefix:      "team-",
+		CommonLabels:    map[string]string{"owner": "team"},
+		Images:          []ImageRule{{Repository: "app", NewTag: "v2"}},
+		ImageRegistries: map[string]string{"docker.io": "mirror.example.com"},
+	}
+	got, err := Manifest(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v1
+      - name: sidecar
+        image: docker.io/proxy:v1
+`, s.Transformers("default")...)
+	require.NoError(t, err)
+	assertManifestEqual(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  labels:
+    owner: team
+  name: team-app
+  namespace: prod
+spec:
+  template:
+    metadata:
+      labels:
+        owner: team
+    spec:
+      containers:
+      - name: app
+        image: app:v2
+      - name: sidecar
+        image: mirror.example.com/library/proxy:v1
+`, got)
+}
+
+func TestRegistryRules(t *testing.T) {
+	assert.Equal(t, []ImageRule{
+		{Registry: "docker.io", NewRegistry: "mirror.example.com/docker"},
+		{Registry: "quay.io", NewRegistry: "mirror.example.com/quay"},
+	}, registryRules(map[string]string{"quay.io": "mirror.example.com/quay", "docker.io": "mirror.example.com/docker"}))
+}
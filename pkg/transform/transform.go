@@ -0,0 +1,58 @@
+// Package transform implements transformers, which change all objects of a manifest of a kind or that refer to each
+// other, where overlays would need a patch for each object.
+package transform
+
+import (
+	"strings"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Transformer changes the objects of a manifest.
+type Transformer interface {
+	// Transform returns the transformed objs. Objects that are changed must be replaced, see update.
+	Transform(objs object.K8sObjects) (object.K8sObjects, error)
+}
+
+// TransformerFunc is a function that implements Transformer.
+type TransformerFunc func(objs object.K8sObjects) (object.K8sObjects, error)
+
+// Transform implements Transformer.
+func (f TransformerFunc) Transform(objs object.K8sObjects) (object.K8sObjects, error) {
+	return f(objs)
+}
+
+// Manifest applies ts in order to the objects of manifest and returns the transformed manifest.
+func Manifest(manifest string, ts ...Transformer) (string, error) {
+	objs, err := object.ParseK8sObjectsFromYAMLManifest(manifest)
+	if err != nil {
+		return "", err
+	}
+	for _, t := range ts {
+		if objs, err = t.Transform(objs); err != nil {
+			return "", err
+		}
+	}
+	var out strings.Builder
+	for _, obj := range objs {
+		y, err := obj.YAML()
+		if err != nil {
+			return "", err
+		}
+		out.Write(y)
+		out.WriteString(object.YAMLSeparator)
+	}
+	return out.String(), nil
+}
+
+// update returns the object for the changed content u of an object. The cached renderings of the original object
+// would be stale, so transformers change a copy of the content and replace the object with the result.
+func update(u *unstructured.Unstructured) *object.K8sObject {
+	return object.NewK8sObject(u, nil, nil)
+}
+
+// objectRef identifies an object of a manifest by kind, namespace and name.
+type objectRef struct {
+	kind, namespace, name string
+}
@@ -0,0 +1,35 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stackrox/k8s-overlay-patch/pkg/object"
+	"github.com/stackrox/k8s-overlay-patch/pkg/util"
+)
+
+// assertManifestEqual checks that the manifests want and got have equal objects in the same order. util.IsYAMLEqual
+// only compares the first document of a manifest.
+func assertManifestEqual(t *testing.T, want, got string) {
+	t.Helper()
+	wantDocs, gotDocs := documents(want), documents(got)
+	if len(wantDocs) != len(gotDocs) {
+		t.Fatalf("got %d objects, want %d:\n%s", len(gotDocs), len(wantDocs), got)
+	}
+	for i := range wantDocs {
+		if !util.IsYAMLEqual(gotDocs[i], wantDocs[i]) {
+			t.Errorf("object %d: got:\n%s\n\nwant:\n%s\nDiff:\n%s\n", i, gotDocs[i], wantDocs[i], util.YAMLDiff(gotDocs[i], wantDocs[i]))
+		}
+	}
+}
+
+// documents returns the non-empty documents of manifest.
+func documents(manifest string) []string {
+	var out []string
+	for _, d := range object.SplitYAMLDocuments(manifest) {
+		if strings.TrimSpace(d) != "" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+package transform
+
+import (
+	names "github.com/stackrox/k8s-overlay-patch/pkg/name"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// podTemplatePaths are the paths of the pod templates of workload kinds.
+var podTemplatePaths = map[string][]string{
+	names.DeploymentStr:  {"spec", "template"},
+	names.StatefulSetStr: {"spec", "template"},
+	names.DaemonSetStr:   {"spec", "template"},
+	names.ReplicaSetStr:  {"spec", "template"},
+	names.JobStr:         {"spec", "template"},
+	names.CronJobStr:     {"spec", "jobTemplate", "spec", "template"},
+}
+
+// podTemplate returns the pod template of u, or nil if u is not a workload with a pod template.
+func podTemplate(u *unstructured.Unstructured) map[string]any {
+	path, ok := podTemplatePaths[u.GetKind()]
+	if !ok {
+		return nil
+	}
+	t, _, _ := unstructured.NestedFieldNoCopy(u.Object, path...)
+	m, _ := t.(map[string]any)
+	return m
+}
+
+// podSpec returns the pod spec of u, a Pod or a workload with a pod template, or nil if it has none.
+func podSpec(u *unstructured.Unstructured) map[string]any {
+	var spec any
+	if u.GetKind() == names.PodStr {
+		spec = u.Object["spec"]
+	} else if t := podTemplate(u); t != nil {
+		spec = t["spec"]
+	}
+	m, _ := spec.(map[string]any)
+	return m
+}
+
+// containers returns the containers, init containers and ephemeral containers of the pod spec.
+func containers(spec map[string]any) []map[string]any {
+	var out []map[string]any
+	for _, key := range []string{"initContainers", "containers", "ephemeralContainers"} {
+		out = append(out, maps(spec[key])...)
+	}
+	return out
+}
+
+// maps returns the maps in l, a list decoded from YAML or JSON.
+func maps(l any) []map[string]any {
+	ll, _ := l.([]any)
+	out := make([]map[string]any, 0, len(ll))
+	for _, e := range ll {
+		if m, ok := e.(map[string]any); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// mapAt returns the map at path in m, without copying it, or nil if there is none.
+func mapAt(m map[string]any, path ...string) map[string]any {
+	v, _, _ := unstructured.NestedFieldNoCopy(m, path...)
+	out, _ := v.(map[string]any)
+	return out
+}
+
+// stringAt returns the string at path in m, or the empty string if there is none.
+func stringAt(m map[string]any, path ...string) string {
+	s, _, _ := unstructured.NestedString(m, path...)
+	return s
+}
@@ -0,0 +1,8 @@
+// Package types contains the overlay API types.
+//
+// The types carry kubebuilder markers and generated DeepCopy methods, so operators can reference them directly from
+// their own CRD types instead of copying and mapping them. For CRDs that are not generated with controller-gen,
+// K8sObjectOverlaySchema returns an equivalent OpenAPI v3 schema fragment, which a test keeps in sync with the types.
+//
+// +kubebuilder:object:generate=true
+package types
@@ -14,6 +14,14 @@
 
 package types
 
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// K8sObjectOverlay is a list of patches applied to the single rendered object identified by kind and name.
+// +kubebuilder:validation:XValidation:rule="!(has(self.remove) && self.remove && has(self.patches) && size(self.patches) > 0)",message="remove and patches cannot be used together"
+// +kubebuilder:validation:XValidation:rule="!has(self.selector) || (has(self.remove) && self.remove)",message="selector can only be used together with remove"
+// +kubebuilder:validation:XValidation:rule="!has(self.selector) || (has(self.selector.matchLabels) && size(self.selector.matchLabels) > 0) || (has(self.selector.matchExpressions) && size(self.selector.matchExpressions) > 0)",message="selector must not be empty"
 type K8sObjectOverlay struct {
 	// Resource API version.
 	ApiVersion string `json:"apiVersion,omitempty"`
@@ -25,26 +33,129 @@ type K8sObjectOverlay struct {
 	Patches []*K8sObjectOverlayPatch `json:"patches,omitempty"`
 	// Optional marks the overlay as optional. If the resource does not exist, the overlay is ignored.
 	Optional bool `json:"optional,omitempty"`
+	// Remove removes the matching objects from the manifest instead of patching them.
+	// Cannot be used together with Patches.
+	Remove bool `json:"remove,omitempty"`
+	// Selector selects the objects to remove by their labels. Kind and Name further restrict the selected objects if
+	// they are set. Unlike other overlays, an overlay with a selector can match several objects.
+	// Can only be used together with Remove.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
 }
 
+// K8sObjectOverlayPatch is a single path/value patch applied to an object.
+// +kubebuilder:validation:XValidation:rule="!(has(self.value) && has(self.verbatim))",message="value and verbatim cannot be used together"
+// +kubebuilder:validation:XValidation:rule="!(has(self.type) && has(self.verbatim))",message="type and verbatim cannot be used together"
+// +kubebuilder:validation:XValidation:rule="!(has(self.valueFrom) && (has(self.value) || has(self.verbatim) || has(self.type)))",message="valueFrom cannot be used together with value, verbatim or type"
 type K8sObjectOverlayPatch struct {
 	// Path of the form a.[key1:value1].b.[:value2]
 	// Where [key1:value1] is a selector for a key-value pair to identify a list element and [:value] is a value
-	// selector to identify a list element in a leaf list.
+	// selector to identify a list element in a leaf list. Map keys with special characters can be quoted, as in
+	// metadata.annotations["app.kubernetes.io/name"].
 	// All path intermediate nodes must exist.
 	Path string `json:"path,omitempty"`
+	// PathSyntax is the syntax of Path and of the path of When, either overlay for the syntax above or jsonpath for
+	// kubectl JSONPath, as in spec.containers[?(@.name=="app")].image.
+	// If unset, paths starting with $ or { are JSONPath and all others use the overlay syntax.
+	// +kubebuilder:validation:Enum=overlay;jsonpath
+	PathSyntax string `json:"pathSyntax,omitempty"`
 	// Value to add, delete or replace.
 	// For add, the path should be a new leaf.
 	// For delete, value should be unset.
 	// For replace, path should reference an existing node.
-	// All values are strings but are converted into appropriate type based on schema.
+	// All values are strings. They are parsed as YAML, and converted to the types the OpenAPI schema of the object
+	// declares for the patched field if the patch is applied with a schema provider.
 	Value string `json:"value,omitempty"`
 	// Verbatim value to add, delete or replace.
 	// Same as Value, however the content is not interpreted as YAML, but treated as literal string instead.
 	// At least one of Value and Verbatim must be empty.
 	Verbatim string `json:"verbatim,omitempty"`
+	// Type of Value. If set, Value is converted to it instead of guessing its type:
+	// string writes Value as is, int, bool and float parse it as a scalar of that type, yaml and json unmarshal it,
+	// base64 writes the base64 encoding of Value, and null writes an explicit null and requires Value to be empty.
+	// Cannot be used together with Verbatim.
+	// +kubebuilder:validation:Enum=string;int;bool;float;yaml;json;base64;null
+	Type string `json:"type,omitempty"`
+	// Template executes Value or Verbatim as a Go text/template for each object the patch is applied to, before it is
+	// used. The template has access to .Object, the object before the patches of its overlay are applied, .Namespace,
+	// the namespace of the object or the default namespace, and .Vars, the variables given to the patch engine, such as
+	// the vars of a patch file. The functions default, quote, b64enc, toYaml and sha256 are available.
+	Template bool `json:"template,omitempty"`
+	// ValueFrom copies the value from another object of the manifest instead of setting Value or Verbatim.
+	// Cannot be used together with Value, Verbatim or Type.
+	ValueFrom *K8sObjectOverlayPatchSource `json:"valueFrom,omitempty"`
+	// When is an optional condition evaluated against the object before the patch is applied.
+	// The patch is skipped if the condition does not hold.
+	When *K8sObjectOverlayPatchCondition `json:"when,omitempty"`
+}
+
+// Types of K8sObjectOverlayPatch values.
+const (
+	ValueTypeString = "string"
+	ValueTypeInt    = "int"
+	ValueTypeBool   = "bool"
+	ValueTypeFloat  = "float"
+	ValueTypeYAML   = "yaml"
+	ValueTypeJSON   = "json"
+	ValueTypeBase64 = "base64"
+	ValueTypeNull   = "null"
+)
+
+// K8sObjectOverlayPatchSource is a value of another object of the manifest that is copied by a patch. If the source
+// object is patched itself, the value is copied after its patches are applied.
+type K8sObjectOverlayPatchSource struct {
+	// Kind of the source object.
+	Kind string `json:"kind,omitempty"`
+	// Name of the source object, which is matched in the same namespace as the objects of overlays.
+	Name string `json:"name,omitempty"`
+	// Path of the value in the source object, in the same form as the patch path.
+	Path string `json:"path,omitempty"`
+}
+
+// K8sObjectOverlayPatchCondition is a condition on the object a patch is applied to.
+// All fields that are set must hold for the condition to hold.
+type K8sObjectOverlayPatchCondition struct {
+	// Path of the node the condition is evaluated against, in the same form as the patch path.
+	// Defaults to the path of the patch.
+	Path string `json:"path,omitempty"`
+	// Exists requires the node at path to exist if true, or to be absent if false.
+	Exists *bool `json:"exists,omitempty"`
+	// Equals requires the node at path to exist and have the given string representation.
+	Equals *string `json:"equals,omitempty"`
+	// Matches requires the node at path to exist and have a string representation that matches the given
+	// regular expression.
+	Matches string `json:"matches,omitempty"`
+	// Expression is a CEL expression that must evaluate to true. The object is available as self.
+	Expression string `json:"expression,omitempty"`
 }
 
+// OverlayObject is the content of a patch file.
+// +kubebuilder:object:generate=false
 type OverlayObject struct {
-	Overlays []*K8sObjectOverlay `json:"overlays,omitempty"`
+	// Vars are variables available to templated patch values as .Vars.
+	Vars map[string]any `json:"vars,omitempty"`
+	// Add are objects added to the manifest before the overlays are applied, so that overlays can target them.
+	Add      []*K8sObjectAddition `json:"add,omitempty"`
+	Overlays []*K8sObjectOverlay  `json:"overlays,omitempty"`
+}
+
+// K8sObjectAddition is an object added to the manifest, either given inline or cloned from an object of the manifest.
+// Exactly one of Object and CloneFrom must be set.
+// +kubebuilder:object:generate=false
+type K8sObjectAddition struct {
+	// Object is the object to add.
+	Object map[string]any `json:"object,omitempty"`
+	// CloneFrom is the object of the manifest that is cloned, with Name as the name of the clone.
+	CloneFrom *K8sObjectReference `json:"cloneFrom,omitempty"`
+	// Name of the clone. Required with CloneFrom.
+	Name string `json:"name,omitempty"`
+	// Patches applied to the added object.
+	Patches []*K8sObjectOverlayPatch `json:"patches,omitempty"`
+}
+
+// K8sObjectReference identifies an object of the manifest, matched in the same namespace as the objects of overlays.
+type K8sObjectReference struct {
+	// Kind of the object.
+	Kind string `json:"kind,omitempty"`
+	// Name of the object.
+	Name string `json:"name,omitempty"`
 }
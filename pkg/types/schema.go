@@ -0,0 +1,23 @@
+package types
+
+import (
+	_ "embed"
+
+	"sigs.k8s.io/yaml"
+)
+
+//go:embed k8sobjectoverlay.schema.yaml
+var k8sObjectOverlaySchemaYAML []byte
+
+// K8sObjectOverlaySchema returns the OpenAPI v3 schema of a single K8sObjectOverlay as JSON.
+// It is meant to be embedded into a CRD as the items schema of an overlays list, e.g. by unmarshaling it into an
+// apiextensionsv1.JSONSchemaProps. The schema includes the descriptions, enums and CEL validation rules of
+// the kubebuilder markers, and is checked against the types by TestK8sObjectOverlaySchema.
+func K8sObjectOverlaySchema() ([]byte, error) {
+	return yaml.YAMLToJSON(k8sObjectOverlaySchemaYAML)
+}
+
+// K8sObjectOverlaySchemaYAML returns the OpenAPI v3 schema of a single K8sObjectOverlay as YAML.
+func K8sObjectOverlaySchemaYAML() string {
+	return string(k8sObjectOverlaySchemaYAML)
+}
@@ -0,0 +1,202 @@
+package types
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+// schemaProps are the parts of an OpenAPI v3 schema that follow from the Go types, doc comments and kubebuilder
+// markers of this package.
+type schemaProps struct {
+	Type                 string                  `json:"type,omitempty"`
+	Description          string                  `json:"description,omitempty"`
+	Enum                 []string                `json:"enum,omitempty"`
+	Required             []string                `json:"required,omitempty"`
+	Properties           map[string]*schemaProps `json:"properties,omitempty"`
+	Items                *schemaProps            `json:"items,omitempty"`
+	AdditionalProperties *schemaProps            `json:"additionalProperties,omitempty"`
+	Validations          []schemaValidation      `json:"x-kubernetes-validations,omitempty"`
+}
+
+type schemaValidation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+var (
+	enumMarker        = regexp.MustCompile(`^\+kubebuilder:validation:Enum=(.*)$`)
+	xValidationMarker = regexp.MustCompile(`^\+kubebuilder:validation:XValidation:rule=("(?:[^"\\]|\\.)*"),message=("(?:[^"\\]|\\.)*")$`)
+)
+
+// TestK8sObjectOverlaySchema compares the hand maintained schema with the one controller-gen generates for
+// K8sObjectOverlay, as far as it follows from the Go types, the doc comments and the kubebuilder markers in overlay.go:
+// types, properties, required fields, descriptions, enums and CEL rules. The descriptions of types of other packages
+// are not compared.
+func TestK8sObjectOverlaySchema(t *testing.T) {
+	var got schemaProps
+	require.NoError(t, yaml.Unmarshal([]byte(K8sObjectOverlaySchemaYAML()), &got))
+	js, err := K8sObjectOverlaySchema()
+	require.NoError(t, err)
+	var fromJSON schemaProps
+	require.NoError(t, yaml.Unmarshal(js, &fromJSON))
+	assert.Equal(t, got, fromJSON)
+
+	docs := typeDocs(t, "overlay.go")
+	want := wantSchema(t, reflect.TypeOf(K8sObjectOverlay{}), docs)
+	keepForeignDescriptions(want, &got, reflect.TypeOf(K8sObjectOverlay{}))
+	assert.Equal(t, want, &got)
+}
+
+// typeDocs returns the doc comments of the types and fields declared in file, by type name and by type name and field
+// name, as in K8sObjectOverlay.Kind.
+func typeDocs(t *testing.T, file string) map[string]*ast.CommentGroup {
+	f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ParseComments)
+	require.NoError(t, err)
+	docs := make(map[string]*ast.CommentGroup)
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			doc := ts.Doc
+			if doc == nil {
+				doc = gd.Doc
+			}
+			docs[ts.Name.Name] = doc
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			for _, field := range st.Fields.List {
+				for _, name := range field.Names {
+					docs[ts.Name.Name+"."+name.Name] = field.Doc
+				}
+			}
+		}
+	}
+	return docs
+}
+
+// wantSchema returns the schema of typ. Doc comments and markers are looked up in docs for the types of this package.
+func wantSchema(t *testing.T, typ reflect.Type, docs map[string]*ast.CommentGroup) *schemaProps {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		return &schemaProps{Type: "string"}
+	case reflect.Bool:
+		return &schemaProps{Type: "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return &schemaProps{Type: "integer"}
+	case reflect.Slice:
+		return &schemaProps{Type: "array", Items: wantSchema(t, typ.Elem(), docs)}
+	case reflect.Map:
+		return &schemaProps{Type: "object", AdditionalProperties: wantSchema(t, typ.Elem(), docs)}
+	case reflect.Struct:
+	default:
+		t.Fatalf("no schema for %s", typ)
+	}
+	local := typ.PkgPath() == reflect.TypeOf(K8sObjectOverlay{}).PkgPath()
+	s := &schemaProps{Type: "object", Properties: make(map[string]*schemaProps)}
+	if local {
+		s.Description, _, s.Validations = parseDoc(t, docs[typ.Name()])
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag := strings.Split(f.Tag.Get("json"), ",")
+		if tag[0] == "" || tag[0] == "-" {
+			continue
+		}
+		prop := wantSchema(t, f.Type, docs)
+		if local {
+			var validations []schemaValidation
+			prop.Description, prop.Enum, validations = parseDoc(t, docs[typ.Name()+"."+f.Name])
+			prop.Validations = append(prop.Validations, validations...)
+		}
+		s.Properties[tag[0]] = prop
+		if !strings.Contains(f.Tag.Get("json"), ",omitempty") {
+			s.Required = append(s.Required, tag[0])
+		}
+	}
+	return s
+}
+
+// parseDoc returns the description, enum values and CEL rules of a doc comment.
+func parseDoc(t *testing.T, doc *ast.CommentGroup) (string, []string, []schemaValidation) {
+	if doc == nil {
+		return "", nil, nil
+	}
+	var lines []string
+	var enum []string
+	var validations []schemaValidation
+	for _, c := range doc.List {
+		line := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+		if m := enumMarker.FindStringSubmatch(line); m != nil {
+			enum = strings.Split(m[1], ";")
+			continue
+		}
+		if m := xValidationMarker.FindStringSubmatch(line); m != nil {
+			rule, err := strconv.Unquote(m[1])
+			require.NoError(t, err)
+			message, err := strconv.Unquote(m[2])
+			require.NoError(t, err)
+			validations = append(validations, schemaValidation{Rule: rule, Message: message})
+			continue
+		}
+		if strings.HasPrefix(line, "+") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), enum, validations
+}
+
+// keepForeignDescriptions copies the descriptions of got to want where typ, the type want is the schema of, is not
+// declared in this package, since their doc comments are not parsed.
+func keepForeignDescriptions(want, got *schemaProps, typ reflect.Type) {
+	if want == nil || got == nil {
+		return
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Slice:
+		keepForeignDescriptions(want.Items, got.Items, typ.Elem())
+		return
+	case reflect.Map:
+		keepForeignDescriptions(want.AdditionalProperties, got.AdditionalProperties, typ.Elem())
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+	local := typ.PkgPath() == reflect.TypeOf(K8sObjectOverlay{}).PkgPath()
+	if !local {
+		want.Description = got.Description
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if want.Properties[name] == nil || got.Properties[name] == nil {
+			continue
+		}
+		if !local {
+			want.Properties[name].Description = got.Properties[name].Description
+		}
+		keepForeignDescriptions(want.Properties[name], got.Properties[name], f.Type)
+	}
+}
@@ -0,0 +1,120 @@
+//go:build !ignore_autogenerated
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package types
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K8sObjectOverlay) DeepCopyInto(out *K8sObjectOverlay) {
+	*out = *in
+	if in.Patches != nil {
+		in, out := &in.Patches, &out.Patches
+		*out = make([]*K8sObjectOverlayPatch, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(K8sObjectOverlayPatch)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K8sObjectOverlay.
+func (in *K8sObjectOverlay) DeepCopy() *K8sObjectOverlay {
+	if in == nil {
+		return nil
+	}
+	out := new(K8sObjectOverlay)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K8sObjectOverlayPatch) DeepCopyInto(out *K8sObjectOverlayPatch) {
+	*out = *in
+	if in.ValueFrom != nil {
+		in, out := &in.ValueFrom, &out.ValueFrom
+		*out = new(K8sObjectOverlayPatchSource)
+		**out = **in
+	}
+	if in.When != nil {
+		in, out := &in.When, &out.When
+		*out = new(K8sObjectOverlayPatchCondition)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K8sObjectOverlayPatch.
+func (in *K8sObjectOverlayPatch) DeepCopy() *K8sObjectOverlayPatch {
+	if in == nil {
+		return nil
+	}
+	out := new(K8sObjectOverlayPatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K8sObjectOverlayPatchCondition) DeepCopyInto(out *K8sObjectOverlayPatchCondition) {
+	*out = *in
+	if in.Exists != nil {
+		in, out := &in.Exists, &out.Exists
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Equals != nil {
+		in, out := &in.Equals, &out.Equals
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K8sObjectOverlayPatchCondition.
+func (in *K8sObjectOverlayPatchCondition) DeepCopy() *K8sObjectOverlayPatchCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(K8sObjectOverlayPatchCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K8sObjectOverlayPatchSource) DeepCopyInto(out *K8sObjectOverlayPatchSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K8sObjectOverlayPatchSource.
+func (in *K8sObjectOverlayPatchSource) DeepCopy() *K8sObjectOverlayPatchSource {
+	if in == nil {
+		return nil
+	}
+	out := new(K8sObjectOverlayPatchSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K8sObjectReference) DeepCopyInto(out *K8sObjectReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K8sObjectReference.
+func (in *K8sObjectReference) DeepCopy() *K8sObjectReference {
+	if in == nil {
+		return nil
+	}
+	out := new(K8sObjectReference)
+	in.DeepCopyInto(out)
+	return out
+}
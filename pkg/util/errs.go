@@ -35,12 +35,17 @@ func (e Errors) String() string {
 	return e.Error()
 }
 
-// ToError returns an error from Errors.
+// Unwrap returns the errors in e, so that errors.Is and errors.As find them.
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// ToError returns an error from Errors, or nil if there are none. The errors in e can be found with errors.As.
 func (e Errors) ToError() error {
 	if len(e) == 0 {
 		return nil
 	}
-	return fmt.Errorf("%s", e)
+	return e
 }
 
 // Dedup removes any duplicated errors.
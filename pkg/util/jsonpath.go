@@ -0,0 +1,284 @@
+package util
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	// PathSyntaxOverlay is the native overlay path syntax, see ParsePath.
+	PathSyntaxOverlay = "overlay"
+	// PathSyntaxJSONPath is the kubectl JSONPath syntax, see ParseJSONPath.
+	PathSyntaxJSONPath = "jsonpath"
+)
+
+// jsonPathSelectorEscaper escapes the characters that are special in the keys and values of selector path elements.
+var jsonPathSelectorEscaper = strings.NewReplacer(
+	PathSeparator, EscapedPathSeparator, KVSeparator, `\:`, KVListSeparator, `\,`, string(MatchExact), `\=`)
+
+// IsJSONPath reports whether path is written in JSONPath syntax, that is whether it starts with $ or {.
+func IsJSONPath(path string) bool {
+	return strings.HasPrefix(path, "$") || strings.HasPrefix(path, "{")
+}
+
+// ParsePathSyntax parses path in the given syntax, PathSyntaxOverlay or PathSyntaxJSONPath. If syntax is empty, path
+// is parsed as JSONPath if IsJSONPath reports so and in the overlay syntax otherwise.
+func ParsePathSyntax(syntax, path string) (Path, error) {
+	switch syntax {
+	case "":
+		if IsJSONPath(path) {
+			return ParseJSONPath(path)
+		}
+		return ParsePath(path)
+	case PathSyntaxOverlay:
+		return ParsePath(path)
+	case PathSyntaxJSONPath:
+		return ParseJSONPath(path)
+	}
+	return nil, &PathSyntaxError{Path: path, Column: 1, Msg: "unknown path syntax " + strconv.Quote(syntax)}
+}
+
+// ParseJSONPath compiles a path in the kubectl JSONPath syntax into the equivalent overlay path. Supported are
+//
+//	$ or {...}                the optional root and template braces
+//	.key, ['key'], ["key"]    map keys, the first key may omit the leading '.'
+//	.*, [*]                   map and list wildcards
+//	[n]                       list indexes, n >= 0
+//	[?(@.a.b=="v")]           list elements whose nested key a.b equals v, compiled to [a.b=v]
+//	[?(@.a=="v" && @.b==1)]   list elements that match all comparisons, compiled to [a=v,b=1]
+//	[?(@=="v")]               leaf list elements equal to v, compiled to [=v]
+//
+// '.', ':', ',' and '=' in the keys and values of filters are escaped with \ in the compiled selectors. Recursive
+// descent, unions, slices and comparisons other than == are not supported, and neither are comparisons with an empty
+// string, which selectors cannot match, or a path that selects the root itself, such as $.
+func ParseJSONPath(path string) (Path, error) {
+	p := &jsonPathParser{pathParser: pathParser{path: path}, end: len(path)}
+	return p.parse()
+}
+
+type jsonPathParser struct {
+	pathParser
+	// end is the offset of the closing '}' of a template, or the length of the path.
+	end int
+}
+
+func (p *jsonPathParser) parse() (Path, error) {
+	if strings.HasPrefix(p.path, "{") {
+		if !strings.HasSuffix(p.path, "}") {
+			return nil, p.errorf(len(p.path), "missing closing '}'")
+		}
+		p.pos, p.end = 1, len(p.path)-1
+	}
+	p.skip("$")
+	out := Path{}
+	for p.pos < p.end {
+		switch c := p.path[p.pos]; {
+		case c == '.':
+			p.pos++
+			switch {
+			case p.skip("."):
+				return nil, p.errorf(p.pos-2, "recursive descent is not supported")
+			case p.skip("*"):
+				out = append(out, MapWildcard)
+			case p.pos < p.end && p.path[p.pos] == '[':
+			default:
+				key, err := p.identifier()
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, key)
+			}
+		case c == '[':
+			pe, err := p.jsonBracket()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, pe)
+		case len(out) == 0:
+			key, err := p.identifier()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, key)
+		default:
+			return nil, p.errorf(p.pos, "unexpected %q, expected '.' or '['", c)
+		}
+	}
+	if len(out) == 0 {
+		return nil, p.errorf(p.pos, "path selects the root, expected a key")
+	}
+	return out, nil
+}
+
+// skip advances past s if the path continues with it and reports whether it did.
+func (p *jsonPathParser) skip(s string) bool {
+	if strings.HasPrefix(p.path[p.pos:p.end], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+// skipSpaces advances past spaces.
+func (p *jsonPathParser) skipSpaces() {
+	for p.pos < p.end && p.path[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// identifier scans an unquoted key up to the next '.' or '['.
+func (p *jsonPathParser) identifier() (string, error) {
+	start := p.pos
+	for p.pos < p.end && p.path[p.pos] != '.' && p.path[p.pos] != '[' {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf(start, "expected key")
+	}
+	return KeyPathElement(p.path[start:p.pos]), nil
+}
+
+// jsonBracket scans a quoted key, wildcard, index or filter, starting at '['.
+func (p *jsonPathParser) jsonBracket() (string, error) {
+	p.pos++
+	var pe string
+	switch {
+	case p.pos < p.end && (p.path[p.pos] == '\'' || p.path[p.pos] == '"'):
+		key, err := p.quotedString()
+		if err != nil {
+			return "", err
+		}
+		pe = KeyPathElement(key)
+	case p.skip("*"):
+		pe = ListWildcard
+	case p.skip("?("):
+		filter, err := p.filter()
+		if err != nil {
+			return "", err
+		}
+		pe = filter
+	default:
+		n := p.pos
+		for n < p.end && p.path[n] != ']' {
+			n++
+		}
+		idx, err := strconv.Atoi(p.path[p.pos:n])
+		if err != nil || idx < 0 {
+			return "", p.errorf(p.pos, "expected quoted key, *, filter or non-negative index")
+		}
+		p.pos = n
+		pe = "[" + strconv.Itoa(idx) + "]"
+	}
+	if !p.skip("]") {
+		return "", p.errorf(p.pos, "expected ']'")
+	}
+	return pe, nil
+}
+
+// quotedString scans a string quoted with ' or ", in which \ escapes the next character.
+func (p *jsonPathParser) quotedString() (string, error) {
+	start := p.pos
+	quote := p.path[p.pos]
+	var sb strings.Builder
+	for p.pos++; p.pos < p.end; p.pos++ {
+		c := p.path[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < p.end:
+			p.pos++
+			sb.WriteByte(p.path[p.pos])
+		case c == quote:
+			p.pos++
+			return sb.String(), nil
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", p.errorf(start, "missing closing %q", quote)
+}
+
+// filter scans the comparisons of a filter after "?(" up to and including the closing ')', and returns the
+// equivalent key/value or value path element.
+func (p *jsonPathParser) filter() (string, error) {
+	var pairs []string
+	leaf := false
+	for {
+		p.skipSpaces()
+		start := p.pos
+		if !p.skip("@") {
+			return "", p.errorf(p.pos, "expected '@'")
+		}
+		var key []string
+		for p.pos < p.end && (p.path[p.pos] == '.' || p.path[p.pos] == '[') {
+			if p.skip(".") {
+				ke := p.pos
+				for p.pos < p.end && strings.IndexByte(".[ =!<>)&|", p.path[p.pos]) < 0 {
+					p.pos++
+				}
+				if p.pos == ke {
+					return "", p.errorf(ke, "expected key")
+				}
+				key = append(key, p.path[ke:p.pos])
+				continue
+			}
+			p.pos++
+			if p.pos == p.end || (p.path[p.pos] != '\'' && p.path[p.pos] != '"') {
+				return "", p.errorf(p.pos, "expected quoted key")
+			}
+			ke, err := p.quotedString()
+			if err != nil {
+				return "", err
+			}
+			if !p.skip("]") {
+				return "", p.errorf(p.pos, "expected ']'")
+			}
+			key = append(key, ke)
+		}
+		p.skipSpaces()
+		if !p.skip("==") {
+			return "", p.errorf(p.pos, "only == comparisons joined by && are supported")
+		}
+		p.skipSpaces()
+		vs := p.pos
+		value, err := p.literal()
+		if err != nil {
+			return "", err
+		}
+		if value == "" {
+			return "", p.errorf(vs, "comparisons with an empty string are not supported")
+		}
+		if len(key) == 0 {
+			leaf = true
+			pairs = append(pairs, "="+jsonPathSelectorEscaper.Replace(value))
+		} else {
+			for i := range key {
+				key[i] = jsonPathSelectorEscaper.Replace(key[i])
+			}
+			pairs = append(pairs, strings.Join(key, PathSeparator)+"="+jsonPathSelectorEscaper.Replace(value))
+		}
+		if leaf && len(pairs) > 1 {
+			return "", p.errorf(start, "comparisons of @ cannot be combined with other comparisons")
+		}
+		p.skipSpaces()
+		if p.skip(")") {
+			return "[" + strings.Join(pairs, KVListSeparator) + "]", nil
+		}
+		if !p.skip("&&") {
+			return "", p.errorf(p.pos, "only == comparisons joined by && are supported")
+		}
+	}
+}
+
+// literal scans a quoted string, or a number, boolean or null up to the next space or ')'.
+func (p *jsonPathParser) literal() (string, error) {
+	if p.pos < p.end && (p.path[p.pos] == '\'' || p.path[p.pos] == '"') {
+		return p.quotedString()
+	}
+	start := p.pos
+	for p.pos < p.end && p.path[p.pos] != ' ' && p.path[p.pos] != ')' {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf(start, "expected value")
+	}
+	return p.path[start:p.pos], nil
+}
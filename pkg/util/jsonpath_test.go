@@ -0,0 +1,209 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		desc    string
+		in      string
+		want    Path
+		wantErr string
+	}{
+		{
+			desc: "keys",
+			in:   "spec.template.spec",
+			want: Path{"spec", "template", "spec"},
+		},
+		{
+			desc: "root",
+			in:   "$.spec.replicas",
+			want: Path{"spec", "replicas"},
+		},
+		{
+			desc: "template",
+			in:   "{.spec.replicas}",
+			want: Path{"spec", "replicas"},
+		},
+		{
+			desc: "filter",
+			in:   `spec.containers[?(@.name=="app")].image`,
+			want: Path{"spec", "containers", "[name=app]", "image"},
+		},
+		{
+			desc: "filter with nested key and escapes",
+			in:   `$.env[?(@.valueFrom.secretKeyRef.name == 'a.b:c,d=e')].name`,
+			want: Path{"env", `[valueFrom.secretKeyRef.name=a\.b\:c\,d\=e]`, "name"},
+		},
+		{
+			desc: "compound filter",
+			in:   `$.spec.ports[?(@.name=="grpc" && @.port==8080)].port`,
+			want: Path{"spec", "ports", "[name=grpc,port=8080]", "port"},
+		},
+		{
+			desc: "filter with quoted key",
+			in:   `$.items[?(@.metadata.labels['app.kubernetes.io/name']=="x")]`,
+			want: Path{"items", `[metadata.labels.app\.kubernetes\.io/name=x]`},
+		},
+		{
+			desc: "leaf filter",
+			in:   `$.spec.args[?(@=="--v=2")]`,
+			want: Path{"spec", "args", `[=--v\=2]`},
+		},
+		{
+			desc: "quoted keys",
+			in:   `$.metadata.annotations['app.kubernetes.io/name']`,
+			want: Path{"metadata", "annotations", "app.kubernetes.io/name"},
+		},
+		{
+			desc: "double quoted key",
+			in:   `$["metadata"].labels`,
+			want: Path{"metadata", "labels"},
+		},
+		{
+			desc: "wildcards and indexes",
+			in:   `$.spec.containers[*].resources.*.cpu.x[0]`,
+			want: Path{"spec", "containers", "[*]", "resources", "*", "cpu", "x", "[0]"},
+		},
+		{
+			desc:    "recursive descent",
+			in:      `$..image`,
+			wantErr: "path $..image: column 2: recursive descent is not supported",
+		},
+		{
+			desc:    "negative index",
+			in:      `$.a[-1]`,
+			wantErr: "path $.a[-1]: column 5: expected quoted key, *, filter or non-negative index",
+		},
+		{
+			desc:    "slice",
+			in:      `$.a[0:2]`,
+			wantErr: "path $.a[0:2]: column 5: expected quoted key, *, filter or non-negative index",
+		},
+		{
+			desc:    "unsupported operator",
+			in:      `$.a[?(@.b!="x")]`,
+			wantErr: `path $.a[?(@.b!="x")]: column 10: only == comparisons joined by && are supported`,
+		},
+		{
+			desc:    "mixed leaf filter",
+			in:      `$.a[?(@.b=="x" && @=="y")]`,
+			wantErr: `path $.a[?(@.b=="x" && @=="y")]: column 19: comparisons of @ cannot be combined with other comparisons`,
+		},
+		{
+			desc:    "empty filter value",
+			in:      `$.a[?(@.name=="")]`,
+			wantErr: `path $.a[?(@.name=="")]: column 15: comparisons with an empty string are not supported`,
+		},
+		{
+			desc:    "empty leaf filter value",
+			in:      `$.a[?(@=='')]`,
+			wantErr: `path $.a[?(@=='')]: column 10: comparisons with an empty string are not supported`,
+		},
+		{
+			desc:    "root",
+			in:      `$`,
+			wantErr: `path $: column 2: path selects the root, expected a key`,
+		},
+		{
+			desc:    "empty template",
+			in:      `{$}`,
+			wantErr: `path {$}: column 3: path selects the root, expected a key`,
+		},
+		{
+			desc:    "unterminated template",
+			in:      `{.a`,
+			wantErr: `path {.a: column 4: missing closing '}'`,
+		},
+		{
+			desc:    "unterminated string",
+			in:      `$.a['b]`,
+			wantErr: `path $.a['b]: column 5: missing closing '\''`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := ParseJSONPath(tt.in)
+			if gotErr := errToString(err); gotErr != tt.wantErr {
+				t.Fatalf("%s: gotErr:%s, wantErr:%s", tt.desc, gotErr, tt.wantErr)
+			}
+			if err == nil && !got.Equals(tt.want) {
+				t.Errorf("%s: got:%q, want:%q", tt.desc, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONPathFilterValues(t *testing.T) {
+	for _, value := range []string{"a.b", "a:b", "a,b", "a=b", "--v=2", `a\:b`} {
+		t.Run(value, func(t *testing.T) {
+			p, err := ParseJSONPath(`$.a[?(@.k.x=='` + strings.ReplaceAll(value, `\`, `\\`) + `')]`)
+			require.NoError(t, err)
+			kvs, err := PathKVs(p[1])
+			require.NoError(t, err)
+			assert.Equal(t, []PathKeyValue{{Key: Path{"k", "x"}, Op: MatchExact, Value: value}}, kvs)
+
+			p, err = ParseJSONPath(`$.a[?(@=='` + strings.ReplaceAll(value, `\`, `\\`) + `')]`)
+			require.NoError(t, err)
+			op, v, err := PathVMatch(p[1])
+			require.NoError(t, err)
+			assert.Equal(t, MatchExact, op)
+			assert.Equal(t, value, v)
+		})
+	}
+}
+
+func TestParsePathSyntax(t *testing.T) {
+	tests := []struct {
+		desc    string
+		syntax  string
+		in      string
+		want    Path
+		wantErr string
+	}{
+		{
+			desc: "overlay by default",
+			in:   "a.[name:n]",
+			want: Path{"a", "[name:n]"},
+		},
+		{
+			desc: "jsonpath by prefix",
+			in:   `$.a[?(@.name=="n")]`,
+			want: Path{"a", "[name=n]"},
+		},
+		{
+			desc:   "explicit jsonpath",
+			syntax: PathSyntaxJSONPath,
+			in:     `a[?(@.name=="n")]`,
+			want:   Path{"a", "[name=n]"},
+		},
+		{
+			desc:   "explicit overlay",
+			syntax: PathSyntaxOverlay,
+			in:     `$.a`,
+			want:   Path{"$", "a"},
+		},
+		{
+			desc:    "unknown",
+			syntax:  "xpath",
+			in:      `a`,
+			wantErr: `path a: column 1: unknown path syntax "xpath"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := ParsePathSyntax(tt.syntax, tt.in)
+			if gotErr := errToString(err); gotErr != tt.wantErr {
+				t.Fatalf("%s: gotErr:%s, wantErr:%s", tt.desc, gotErr, tt.wantErr)
+			}
+			if err == nil && !got.Equals(tt.want) {
+				t.Errorf("%s: got:%q, want:%q", tt.desc, got, tt.want)
+			}
+		})
+	}
+}
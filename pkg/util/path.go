@@ -28,6 +28,9 @@ const (
 	// KVSeparator is the separator between the key and value in a key/value path element,
 	KVSeparator     = string(kvSeparatorRune)
 	kvSeparatorRune = ':'
+	// KVListSeparator is the separator between the key/value pairs of a compound key/value path element.
+	KVListSeparator     = string(kvListSeparatorRune)
+	kvListSeparatorRune = ','
 
 	// InsertIndex is the index that means "insert" when setting values
 	InsertIndex = -1
@@ -36,16 +39,43 @@ const (
 	pathSeparatorRune = '.'
 	// EscapedPathSeparator is what to use when the path shouldn't separate
 	EscapedPathSeparator = "\\" + PathSeparator
+
+	// ListWildcard is the path element that selects every element of a list.
+	ListWildcard = "[*]"
+	// MapWildcard is the path element that selects every entry of a map.
+	MapWildcard = "*"
+
+	// literalKeyEscape starts the path elements of map keys that would otherwise be read as a wildcard or selector,
+	// see KeyPathElement.
+	literalKeyEscape = `\`
 )
 
 // ValidKeyRegex is a regex for a valid path key element.
 var ValidKeyRegex = regexp.MustCompile("^[a-zA-Z0-9_-]*$")
 
+// validSelectorKeyRegex is a regex for a valid element of the key of a key/value path element, after unescaping.
+var validSelectorKeyRegex = regexp.MustCompile("^[a-zA-Z0-9_./-]+$")
+
+// selectorUnescaper removes the escapes from the keys and values of key/value and value path elements.
+var selectorUnescaper = strings.NewReplacer(EscapedPathSeparator, PathSeparator, `\:`, ":", `\,`, ",", `\=`, "=")
+
 // Path is a path in slice form.
 type Path []string
 
-// PathFromString converts a string path of form a.b.c to a string slice representation.
+// PathFromString converts a string path of form a.b.c to a string slice representation. Paths that ParsePath rejects
+// are split at unescaped separators instead, with empty elements dropped, as before ParsePath existed.
+//
+// Deprecated: use ParsePath, which reports malformed paths.
 func PathFromString(path string) Path {
+	if p, err := ParsePath(path); err == nil {
+		return p
+	}
+	return splitPath(path)
+}
+
+// splitPath splits a string path of form a.b.c at unescaped separators, dropping empty elements. Elements of the form
+// node[expr] are split into node and [expr].
+func splitPath(path string) Path {
 	path = filepath.Clean(path)
 	path = strings.TrimPrefix(path, PathSeparator)
 	path = strings.TrimSuffix(path, PathSeparator)
@@ -67,9 +97,33 @@ func PathFromString(path string) Path {
 	return r
 }
 
+// KeyPathElement returns the path element for the map key key. Keys that would be read as a wildcard or selector, such
+// as * and [x], and keys starting with \ are escaped with a leading \, so that they are taken literally.
+func KeyPathElement(key string) string {
+	if IsWildcardPathElement(key) || strings.HasPrefix(key, "[") || strings.HasPrefix(key, literalKeyEscape) {
+		return literalKeyEscape + key
+	}
+	return key
+}
+
+// PathKey returns the map key the key path element pe refers to, see KeyPathElement.
+func PathKey(pe string) string {
+	return strings.TrimPrefix(pe, literalKeyEscape)
+}
+
 // String converts a string slice path representation of form ["a", "b", "c"] to a string representation like "a.b.c".
+// Keys that contain separators, brackets, quotes or backslashes are written as quoted keys, as in a["b.c"].
 func (p Path) String() string {
-	return strings.Join(p, PathSeparator)
+	out := make([]string, len(p))
+	for i, pe := range p {
+		if strings.HasPrefix(pe, literalKeyEscape) {
+			pe = quoteKey(PathKey(pe))
+		} else if _, ok := RemoveBrackets(pe); !ok && !IsWildcardPathElement(pe) && needsQuoting(pe) {
+			pe = quoteKey(pe)
+		}
+		out[i] = pe
+	}
+	return strings.Join(out, PathSeparator)
 }
 
 func (p Path) Equals(p2 Path) bool {
@@ -84,18 +138,35 @@ func (p Path) Equals(p2 Path) bool {
 	return true
 }
 
+// ParseYAMLPath parses a path string with ParsePath and lower cases the first letter of each path element.
+func ParseYAMLPath(path string) (Path, error) {
+	p, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return toYAMLPath(p), nil
+}
+
 // ToYAMLPath converts a path string to path such that the first letter of each path element is lower case.
+//
+// Deprecated: use ParseYAMLPath, which reports malformed paths.
 func ToYAMLPath(path string) Path {
-	p := PathFromString(path)
-	for i := range p {
-		p[i] = firstCharToLowerCase(p[i])
-	}
-	return p
+	return toYAMLPath(PathFromString(path))
 }
 
 // ToYAMLPathString converts a path string such that the first letter of each path element is lower case.
+//
+// Deprecated: use ParseYAMLPath and Path.String, which report malformed paths.
 func ToYAMLPathString(path string) string {
-	return ToYAMLPath(path).String()
+	return toYAMLPath(PathFromString(path)).String()
+}
+
+// toYAMLPath lower cases the first letter of each path element of p in place and returns it.
+func toYAMLPath(p Path) Path {
+	for i := range p {
+		p[i] = firstCharToLowerCase(p[i])
+	}
+	return p
 }
 
 // IsValidPathElement reports whether pe is a valid path element.
@@ -105,16 +176,23 @@ func IsValidPathElement(pe string) bool {
 
 // IsKVPathElement report whether pe is a key/value path element.
 func IsKVPathElement(pe string) bool {
-	pe, ok := RemoveBrackets(pe)
-	if !ok {
-		return false
-	}
+	_, err := PathKVs(pe)
+	return err == nil
+}
 
-	kv := splitEscaped(pe, kvSeparatorRune)
-	if len(kv) != 2 || len(kv[0]) == 0 || len(kv[1]) == 0 {
-		return false
+// IsWildcardPathElement reports whether pe is a list or map wildcard path element.
+func IsWildcardPathElement(pe string) bool {
+	return pe == ListWildcard || pe == MapWildcard
+}
+
+// HasWildcard reports whether any element of p is a wildcard path element.
+func (p Path) HasWildcard() bool {
+	for _, pe := range p {
+		if IsWildcardPathElement(pe) {
+			return true
+		}
 	}
-	return IsValidPathElement(kv[0])
+	return false
 }
 
 // IsVPathElement report whether pe is a value path element.
@@ -138,15 +216,69 @@ func IsNPathElement(pe string) bool {
 	return err == nil && n >= InsertIndex
 }
 
+// PathKeyValue is a single key/value pair of a key/value path element.
+type PathKeyValue struct {
+	// Key is the path of the key relative to the selected list element.
+	Key Path
+	// Op is how the value at Key is compared with Value.
+	Op MatchOp
+	// Value is the string representation of the value at Key, or the pattern it must match.
+	Value string
+}
+
 // PathKV returns the key and value string parts of the entire key/value path element.
-// It returns an error if pe is not a key/value path element.
+// It returns an error if pe is not a key/value path element with a single key/value pair.
 func PathKV(pe string) (k, v string, err error) {
-	if !IsKVPathElement(pe) {
-		return "", "", fmt.Errorf("%s is not a valid key:value path element", pe)
+	kvs, err := PathKVs(pe)
+	if err != nil {
+		return "", "", err
+	}
+	if len(kvs) != 1 {
+		return "", "", fmt.Errorf("%s is not a single key:value path element", pe)
+	}
+	return kvs[0].Key.String(), kvs[0].Value, nil
+}
+
+// PathKVs returns the key/value pairs of a key/value path element of the form [k1:v1,k2.k3:v2]. Keys with
+// separators refer to nested keys. Separators, ':' and ',' in keys and values must be escaped with \, as must '=' in
+// keys; \= in values stands for '=' as well.
+// Instead of ':', pairs can use one of the match operators =, ^=, *= and ~=, see MatchOp.
+// It returns an error if pe is not a key/value path element.
+func PathKVs(pe string) ([]PathKeyValue, error) {
+	inner, ok := RemoveBrackets(pe)
+	if !ok || inner == "" {
+		return nil, fmt.Errorf("%s is not a valid key:value path element", pe)
+	}
+	var out []PathKeyValue
+	for _, pair := range splitEscaped(inner, kvListSeparatorRune) {
+		kv, err := pathKeyValue(pair)
+		if err != nil {
+			// Not a list of pairs, try the whole element as a single pair with an unescaped ',' in the value.
+			if kv, err := pathKeyValue(inner); err == nil {
+				return []PathKeyValue{kv}, nil
+			}
+			return nil, fmt.Errorf("%s is not a valid key:value path element", pe)
+		}
+		out = append(out, kv)
 	}
-	pe, _ = RemoveBrackets(pe)
-	kv := splitEscaped(pe, kvSeparatorRune)
-	return kv[0], kv[1], nil
+	return out, nil
+}
+
+// pathKeyValue parses a single key:value or key<op>value pair of a key/value path element.
+func pathKeyValue(pair string) (PathKeyValue, error) {
+	k, op, v, ok := splitSelector(pair)
+	if !ok || len(k) == 0 || len(v) == 0 {
+		return PathKeyValue{}, fmt.Errorf("%s is not a valid key:value pair", pair)
+	}
+	var key Path
+	for _, ke := range splitEscaped(k, pathSeparatorRune) {
+		ke = selectorUnescaper.Replace(ke)
+		if !validSelectorKeyRegex.MatchString(ke) {
+			return PathKeyValue{}, fmt.Errorf("%s is not a valid key:value pair", pair)
+		}
+		key = append(key, ke)
+	}
+	return PathKeyValue{Key: key, Op: op, Value: unescapeSelectorValue(op, v)}, nil
 }
 
 // PathV returns the value string part of the entire value path element.
@@ -155,13 +287,13 @@ func PathV(pe string) (string, error) {
 	// For :val, return the value only
 	if IsVPathElement(pe) {
 		v, _ := RemoveBrackets(pe)
-		return v[1:], nil
+		return strings.ReplaceAll(v[1:], EscapedPathSeparator, PathSeparator), nil
 	}
 
 	// For key:val, return the whole thing
 	v, _ := RemoveBrackets(pe)
 	if len(v) > 0 {
-		return v, nil
+		return strings.ReplaceAll(v, EscapedPathSeparator, PathSeparator), nil
 	}
 	return "", fmt.Errorf("%s is not a valid value path element", pe)
 }
@@ -204,6 +336,35 @@ func splitEscaped(s string, r rune) []string {
 	return out
 }
 
+// splitOutsideBrackets splits a string using the rune r as a separator. It does not split on r if it's prefixed by \
+// or inside [].
+func splitOutsideBrackets(s string, r rune) []string {
+	var prev rune
+	if len(s) == 0 {
+		return []string{}
+	}
+	prevIdx, depth := 0, 0
+	var out []string
+	for i, c := range s {
+		switch {
+		case prev == '\\':
+		case c == '[':
+			depth++
+		case c == ']' && depth > 0:
+			depth--
+		case c == r && depth == 0:
+			out = append(out, s[prevIdx:i])
+			prevIdx = i + 1
+		}
+		prev = c
+	}
+	out = append(out, s[prevIdx:])
+	return out
+}
+
 func firstCharToLowerCase(s string) string {
+	if s == "" {
+		return s
+	}
 	return strings.ToLower(s[0:1]) + s[1:]
 }
@@ -0,0 +1,177 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// PathSyntaxError is returned by ParsePath for a malformed path.
+type PathSyntaxError struct {
+	// Path is the path that failed to parse.
+	Path string
+	// Column is the 1-based column, in runes, of the problem in Path.
+	Column int
+	// Msg describes the problem.
+	Msg string
+}
+
+func (e *PathSyntaxError) Error() string {
+	return fmt.Sprintf("path %s: column %d: %s", e.Path, e.Column, e.Msg)
+}
+
+// ParsePath parses a string path into its path elements. The grammar is
+//
+//	path    = [ "." ] element { ( "." element | bracket ) } [ "." ]
+//	element = key | bracket
+//	key     = one or more characters other than unescaped "." and "["
+//	bracket = "[" quoted "]" | "[" selector "]"
+//	quoted  = '"' { character other than '"' and "\" | '\"' | "\\" } '"'
+//
+// A quoted key such as metadata.annotations["app.kubernetes.io/name"] is a single map key element; '.' and any other
+// character in it are taken literally, so ["*"] and ["[x]"] refer to the keys * and [x] rather than to a wildcard and
+// a selector. In unquoted keys, \. and \[ stand for a literal '.' and '['. Keys are returned as KeyPathElement
+// returns them. Selectors, such as [key:value], [0] and [*], are returned with their brackets and escapes as they are
+// and interpreted when the path is resolved.
+func ParsePath(path string) (Path, error) {
+	p := &pathParser{path: path}
+	return p.parse()
+}
+
+type pathParser struct {
+	path string
+	pos  int
+}
+
+func (p *pathParser) parse() (Path, error) {
+	out := Path{}
+	if p.path == "" {
+		return out, nil
+	}
+	if p.path[0] == pathSeparatorRune {
+		p.pos++
+	}
+	for p.pos < len(p.path) {
+		var pe string
+		var err error
+		if p.path[p.pos] == '[' {
+			pe, err = p.bracket()
+		} else {
+			pe, err = p.key()
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pe)
+		if p.pos == len(p.path) {
+			break
+		}
+		switch p.path[p.pos] {
+		case '[':
+		case pathSeparatorRune:
+			p.pos++
+		default:
+			return nil, p.errorf(p.pos, "unexpected %q after ']', expected '.' or '['", p.path[p.pos])
+		}
+	}
+	return out, nil
+}
+
+// key scans an unquoted key up to the next unescaped '.' or '['.
+func (p *pathParser) key() (string, error) {
+	start := p.pos
+	var sb strings.Builder
+	for p.pos < len(p.path) {
+		c := p.path[p.pos]
+		if c == pathSeparatorRune || c == '[' {
+			break
+		}
+		if c == '\\' && p.pos+1 < len(p.path) && (p.path[p.pos+1] == pathSeparatorRune || p.path[p.pos+1] == '[') {
+			p.pos++
+			c = p.path[p.pos]
+		}
+		sb.WriteByte(c)
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf(start, "empty path element")
+	}
+	key := sb.String()
+	if key == MapWildcard {
+		return key, nil
+	}
+	return KeyPathElement(key), nil
+}
+
+// bracket scans a quoted key or a selector, starting at '['.
+func (p *pathParser) bracket() (string, error) {
+	start := p.pos
+	if p.pos+1 < len(p.path) && p.path[p.pos+1] == '"' {
+		return p.quoted()
+	}
+	depth := 0
+	for ; p.pos < len(p.path); p.pos++ {
+		switch p.path[p.pos] {
+		case '\\':
+			p.pos++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				p.pos++
+				if p.pos-start == 2 {
+					return "", p.errorf(start, "empty brackets")
+				}
+				return p.path[start:p.pos], nil
+			}
+		}
+	}
+	return "", p.errorf(start, "missing closing ']'")
+}
+
+// quoted scans a quoted key of the form ["key"], starting at '['.
+func (p *pathParser) quoted() (string, error) {
+	quote := p.pos + 1
+	var sb strings.Builder
+	for p.pos = quote + 1; p.pos < len(p.path); p.pos++ {
+		c := p.path[p.pos]
+		switch c {
+		case '\\':
+			if p.pos+1 == len(p.path) || (p.path[p.pos+1] != '"' && p.path[p.pos+1] != '\\') {
+				return "", p.errorf(p.pos, `invalid escape in quoted key, only \" and \\ are allowed`)
+			}
+			p.pos++
+			sb.WriteByte(p.path[p.pos])
+		case '"':
+			p.pos++
+			if p.pos == len(p.path) || p.path[p.pos] != ']' {
+				return "", p.errorf(p.pos, "expected ']' after quoted key")
+			}
+			p.pos++
+			return KeyPathElement(sb.String()), nil
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", p.errorf(quote, "missing closing '\"'")
+}
+
+// errorf returns a PathSyntaxError at byte offset pos.
+func (p *pathParser) errorf(pos int, format string, args ...any) error {
+	return &PathSyntaxError{
+		Path:   p.path,
+		Column: utf8.RuneCountInString(p.path[:pos]) + 1,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+// needsQuoting reports whether the key path element pe must be written as a quoted key to parse back to itself.
+func needsQuoting(pe string) bool {
+	return pe == "" || strings.ContainsAny(pe, `.[]"\`)
+}
+
+// quoteKey returns the key path element pe as a quoted key.
+func quoteKey(pe string) string {
+	return `["` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(pe) + `"]`
+}
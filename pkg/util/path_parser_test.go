@@ -0,0 +1,178 @@
+package util
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		desc    string
+		in      string
+		want    Path
+		wantErr string
+	}{
+		{
+			desc: "empty",
+			in:   "",
+			want: Path{},
+		},
+		{
+			desc: "keys",
+			in:   "a.b.c",
+			want: Path{"a", "b", "c"},
+		},
+		{
+			desc: "surround-periods",
+			in:   ".a.",
+			want: Path{"a"},
+		},
+		{
+			desc: "selectors",
+			in:   `a.[name:n1].b[0].c.[*].d.*.e.[v\.1]`,
+			want: Path{"a", "[name:n1]", "b", "[0]", "c", "[*]", "d", "*", "e", `[v\.1]`},
+		},
+		{
+			desc: "escaped separators",
+			in:   `a.b\.c.d\[0]`,
+			want: Path{"a", "b.c", "d[0]"},
+		},
+		{
+			desc: "quoted key",
+			in:   `metadata.annotations["app.kubernetes.io/name"]`,
+			want: Path{"metadata", "annotations", "app.kubernetes.io/name"},
+		},
+		{
+			desc: "quoted key after separator",
+			in:   `metadata.annotations.["app.kubernetes.io/name"].x`,
+			want: Path{"metadata", "annotations", "app.kubernetes.io/name", "x"},
+		},
+		{
+			desc: "quoted key is not cleaned",
+			in:   `a["example.com//x/../y"]`,
+			want: Path{"a", "example.com//x/../y"},
+		},
+		{
+			desc: "escaped quotes",
+			in:   `a["say \"hi\" \\o/"]`,
+			want: Path{"a", `say "hi" \o/`},
+		},
+		{
+			desc: "brackets in quoted key",
+			in:   `a["[b]c"]`,
+			want: Path{"a", `\[b]c`},
+		},
+		{
+			desc: "nested brackets in selector",
+			in:   `a.[b[0]:c].d`,
+			want: Path{"a", "[b[0]:c]", "d"},
+		},
+		{
+			desc:    "empty element",
+			in:      "a..b",
+			wantErr: "path a..b: column 3: empty path element",
+		},
+		{
+			desc:    "unterminated selector",
+			in:      "a.[name:n1.b",
+			wantErr: "path a.[name:n1.b: column 3: missing closing ']'",
+		},
+		{
+			desc:    "unterminated quote",
+			in:      `a["b.c]`,
+			wantErr: `path a["b.c]: column 3: missing closing '"'`,
+		},
+		{
+			desc:    "missing bracket after quote",
+			in:      `a["b"c]`,
+			wantErr: `path a["b"c]: column 6: expected ']' after quoted key`,
+		},
+		{
+			desc:    "invalid escape",
+			in:      `a["b\.c"]`,
+			wantErr: `path a["b\.c"]: column 5: invalid escape in quoted key, only \" and \\ are allowed`,
+		},
+		{
+			desc:    "garbage after bracket",
+			in:      `a.[0]b`,
+			wantErr: `path a.[0]b: column 6: unexpected 'b' after ']', expected '.' or '['`,
+		},
+		{
+			desc:    "empty brackets",
+			in:      `a.[]`,
+			wantErr: `path a.[]: column 3: empty brackets`,
+		},
+		{
+			desc: "quoted wildcard",
+			in:   `a["*"]`,
+			want: Path{"a", `\*`},
+		},
+		{
+			desc: "quoted selector",
+			in:   `a["[0]"]`,
+			want: Path{"a", `\[0]`},
+		},
+		{
+			desc:    "column counts runes",
+			in:      `ä.ö..b`,
+			wantErr: `path ä.ö..b: column 5: empty path element`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := ParsePath(tt.in)
+			if gotErr := errToString(err); gotErr != tt.wantErr {
+				t.Fatalf("%s: gotErr:%s, wantErr:%s", tt.desc, gotErr, tt.wantErr)
+			}
+			if err != nil {
+				var pse *PathSyntaxError
+				if !errors.As(err, &pse) {
+					t.Errorf("%s: got error of type %T, want *PathSyntaxError", tt.desc, err)
+				}
+				return
+			}
+			if !got.Equals(tt.want) {
+				t.Errorf("%s: got:%q, want:%q", tt.desc, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathStringRoundTrip(t *testing.T) {
+	tests := []struct {
+		desc string
+		in   Path
+		want string
+	}{
+		{
+			desc: "plain",
+			in:   Path{"a", "[name:n1]", "[*]", "*", "b"},
+			want: "a.[name:n1].[*].*.b",
+		},
+		{
+			desc: "quoted",
+			in:   Path{"metadata", "annotations", "app.kubernetes.io/name"},
+			want: `metadata.annotations.["app.kubernetes.io/name"]`,
+		},
+		{
+			desc: "escapes",
+			in:   Path{"a", `b"c\d`},
+			want: `a.["b\"c\\d"]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got := tt.in.String()
+			if got != tt.want {
+				t.Fatalf("%s: got:%s, want:%s", tt.desc, got, tt.want)
+			}
+			back, err := ParsePath(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !back.Equals(tt.in) {
+				t.Errorf("%s: got:%q, want:%q", tt.desc, back, tt.in)
+			}
+		})
+	}
+}
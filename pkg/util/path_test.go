@@ -16,6 +16,7 @@ package util
 
 import (
 	"errors"
+	"reflect"
 	"testing"
 )
 
@@ -102,6 +103,43 @@ func TestIsNPathElement(t *testing.T) {
 	}
 }
 
+func TestIsWildcardPathElement(t *testing.T) {
+	tests := []struct {
+		desc   string
+		in     string
+		expect bool
+	}{
+		{
+			desc:   "list",
+			in:     "[*]",
+			expect: true,
+		},
+		{
+			desc:   "map",
+			in:     "*",
+			expect: true,
+		},
+		{
+			desc:   "key",
+			in:     "a*",
+			expect: false,
+		},
+		{
+			desc:   "value",
+			in:     "[a*]",
+			expect: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := IsWildcardPathElement(tt.in); got != tt.expect {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
+			}
+		})
+	}
+}
+
 func stringSlicesEqual(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
@@ -135,28 +173,93 @@ func TestPathFromString(t *testing.T) {
 			in:     ".a.",
 			expect: Path{"a"},
 		},
+		{
+			desc:   "wildcards",
+			in:     "a[*].b.*",
+			expect: Path{"a", "[*]", "b", "*"},
+		},
+		{
+			desc:   "separators in brackets",
+			in:     `a.[b.c:d.e].f[g\.h:i].j\.k`,
+			expect: Path{"a", "[b.c:d.e]", "f", `[g\.h:i]`, "j.k"},
+		},
+		{
+			desc:   "quoted key",
+			in:     `metadata.annotations["example.com//x"]`,
+			expect: Path{"metadata", "annotations", "example.com//x"},
+		},
+		{
+			desc:   "quoted keys read as wildcards or selectors",
+			in:     `a["*"]["[*]"]["[x:y]"]["\\b"]`,
+			expect: Path{"a", `\*`, `\[*]`, `\[x:y]`, `\\b`},
+		},
+		{
+			desc:   "escaped bracket key",
+			in:     `a.\[x]`,
+			expect: Path{"a", `\[x]`},
+		},
+		{
+			desc:   "malformed path split at separators",
+			in:     `a..b.[]`,
+			expect: Path{"a", "b", "[]"},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.desc, func(t *testing.T) {
-			if got := PathFromString(tt.in); !got.Equals(tt.expect) {
+			got := PathFromString(tt.in)
+			if !got.Equals(tt.expect) {
 				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
 			}
+			if _, err := ParsePath(tt.in); err == nil && tt.in != "" {
+				// Paths print as paths that parse back to them.
+				if again, err := ParsePath(got.String()); err != nil || !again.Equals(got) {
+					t.Errorf("%s: %s parses to %v, %v", tt.desc, got.String(), again, err)
+				}
+			}
 		})
 	}
 }
 
+func TestKeyPathElement(t *testing.T) {
+	for key, want := range map[string]string{
+		"a":      "a",
+		"*":      `\*`,
+		"[*]":    `\[*]`,
+		"[0]":    `\[0]`,
+		"[x":     `\[x`,
+		`\a`:     `\\a`,
+		"a*":     "a*",
+		"a[0]":   "a[0]",
+		"a.b[c]": "a.b[c]",
+	} {
+		if got := KeyPathElement(key); got != want {
+			t.Errorf("KeyPathElement(%q) = %q, want %q", key, got, want)
+		}
+		if got := PathKey(KeyPathElement(key)); got != key {
+			t.Errorf("PathKey(KeyPathElement(%q)) = %q", key, got)
+		}
+	}
+}
+
 func TestToYAMLPath(t *testing.T) {
 	tests := []struct {
-		desc   string
-		in     string
-		expect Path
+		desc    string
+		in      string
+		expect  Path
+		wantErr string
 	}{
 		{
 			desc:   "all-uppercase",
 			in:     "A.B.C.D",
 			expect: Path{"a", "b", "c", "d"},
 		},
+		{
+			desc:    "malformed",
+			in:      "A..B",
+			expect:  Path{"a", "b"},
+			wantErr: "path A..B: column 3: empty path element",
+		},
 	}
 
 	for _, tt := range tests {
@@ -164,6 +267,16 @@ func TestToYAMLPath(t *testing.T) {
 			if got := ToYAMLPath(tt.in); !got.Equals(tt.expect) {
 				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
 			}
+			if got, want := ToYAMLPathString(tt.in), tt.expect.String(); got != want {
+				t.Errorf("%s: expect %s got %s", tt.desc, want, got)
+			}
+			got, err := ParseYAMLPath(tt.in)
+			if gotErr := errToString(err); gotErr != tt.wantErr {
+				t.Fatalf("%s: gotErr:%s, wantErr:%s", tt.desc, gotErr, tt.wantErr)
+			}
+			if err == nil && !got.Equals(tt.expect) {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.expect, got)
+			}
 		})
 	}
 }
@@ -204,6 +317,28 @@ func TestIsKVPathElement(t *testing.T) {
 			in:     "[1:2",
 			expect: false,
 		},
+		{
+			desc:   "compound",
+			in:     "[a:1,b.c:2]",
+			expect: true,
+		},
+		{
+			// Before match operators, '=' was not allowed in keys. It is now the exact match operator, so this
+			// selects elements whose vv2 is "foo:bar".
+			desc:   "regex",
+			in:     "[vv2=foo:bar]",
+			expect: true,
+		},
+		{
+			desc:   "invalid-key",
+			in:     "[vv 2:bar]",
+			expect: false,
+		},
+		{
+			desc:   "operator",
+			in:     "[name^=grpc]",
+			expect: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -288,6 +423,78 @@ func TestPathKV(t *testing.T) {
 	}
 }
 
+func TestPathKVs(t *testing.T) {
+	tests := []struct {
+		desc    string
+		in      string
+		want    []PathKeyValue
+		wantErr bool
+	}{
+		{
+			desc: "single",
+			in:   "[name:n1]",
+			want: []PathKeyValue{{Key: Path{"name"}, Op: MatchExact, Value: "n1"}},
+		},
+		{
+			desc: "compound",
+			in:   "[name:grpc,protocol:TCP]",
+			want: []PathKeyValue{{Key: Path{"name"}, Op: MatchExact, Value: "grpc"}, {Key: Path{"protocol"}, Op: MatchExact, Value: "TCP"}},
+		},
+		{
+			desc: "nested",
+			in:   "[valueFrom.secretKeyRef.name:foo.bar]",
+			want: []PathKeyValue{{Key: Path{"valueFrom", "secretKeyRef", "name"}, Op: MatchExact, Value: "foo.bar"}},
+		},
+		{
+			desc: "escapes",
+			in:   `[labels.app\.kubernetes\.io/name:a\:b\,c]`,
+			want: []PathKeyValue{{Key: Path{"labels", "app.kubernetes.io/name"}, Op: MatchExact, Value: "a:b,c"}},
+		},
+		{
+			desc: "unescaped comma in value",
+			in:   "[name:a,b]",
+			want: []PathKeyValue{{Key: Path{"name"}, Op: MatchExact, Value: "a,b"}},
+		},
+		{
+			desc: "operators",
+			in:   `[a=x:y,b^=p,c*=*.example.com,d~=v[0-9]+\,?]`,
+			want: []PathKeyValue{
+				{Key: Path{"a"}, Op: MatchExact, Value: "x:y"},
+				{Key: Path{"b"}, Op: MatchPrefix, Value: "p"},
+				{Key: Path{"c"}, Op: MatchGlob, Value: "*.example.com"},
+				{Key: Path{"d"}, Op: MatchRegex, Value: `v[0-9]+\,?`},
+			},
+		},
+		{
+			desc:    "empty key",
+			in:      "[name:a,:b]",
+			wantErr: true,
+		},
+		{
+			desc:    "empty nested key",
+			in:      "[a..b:c]",
+			wantErr: true,
+		},
+		{
+			desc:    "empty",
+			in:      "[]",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := PathKVs(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s: got error %v, want error %v", tt.desc, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: expect %v got %v", tt.desc, tt.want, got)
+			}
+		})
+	}
+}
+
 func TestPathV(t *testing.T) {
 	tests := []struct {
 		desc string
@@ -0,0 +1,204 @@
+package util
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// MatchOp is how a selector path element compares the values of list elements with its value.
+type MatchOp string
+
+const (
+	// MatchExact selects values equal to the selector value, as in [key=value] or [=value]. [key:value] also matches
+	// exactly.
+	MatchExact MatchOp = "="
+	// MatchPrefix selects values starting with the selector value, as in [key^=value] or [^=value].
+	MatchPrefix MatchOp = "^="
+	// MatchGlob selects values matching the selector glob pattern, as in [key*=pattern] or [*=pattern].
+	// See path.Match for the pattern syntax.
+	MatchGlob MatchOp = "*="
+	// MatchRegex selects values entirely matching the selector regular expression, as in [key~=regex] or [~=regex].
+	MatchRegex MatchOp = "~="
+	// MatchUnanchoredRegex selects values containing a match of the selector regular expression. It is used by value
+	// path elements without an operator, [value] and [:value].
+	MatchUnanchoredRegex MatchOp = ""
+)
+
+// operatorMatchOps are the match operators that can be used in place of ':' in selectors, in the order they are tried.
+var operatorMatchOps = []MatchOp{MatchPrefix, MatchGlob, MatchRegex, MatchExact}
+
+// MatchValue reports whether s matches pattern using op.
+// It returns an error if pattern is not a valid glob pattern or regular expression.
+func MatchValue(op MatchOp, pattern, s string) (bool, error) {
+	switch op {
+	case MatchExact:
+		return s == pattern, nil
+	case MatchPrefix:
+		return strings.HasPrefix(s, pattern), nil
+	case MatchGlob:
+		match, err := path.Match(pattern, s)
+		if err != nil {
+			return false, fmt.Errorf("invalid glob pattern %q: %v", pattern, err)
+		}
+		return match, nil
+	case MatchRegex, MatchUnanchoredRegex:
+		re, err := compileRegex(op, pattern)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(s), nil
+	}
+	return false, fmt.Errorf("unknown match operator %q", op)
+}
+
+// regexKey identifies a compiled selector regular expression.
+type regexKey struct {
+	op      MatchOp
+	pattern string
+}
+
+// maxCachedRegexes bounds the number of compiled selector regular expressions kept in regexes. The patterns come from
+// the overlays of custom resources, so a long-running operator would otherwise keep every pattern it ever saw.
+const maxCachedRegexes = 1024
+
+// regexes caches the compiled selector regular expressions by regexKey. ValidatePath compiles the regular expressions
+// of a path when it is parsed, so that matching the elements of lists with it does not compile them again. The cache is
+// emptied when it holds maxCachedRegexes expressions.
+var regexes = struct {
+	sync.Mutex
+	m map[regexKey]*regexp.Regexp
+}{m: make(map[regexKey]*regexp.Regexp)}
+
+// compileRegex returns the compiled regular expression of pattern for op, MatchRegex or MatchUnanchoredRegex.
+func compileRegex(op MatchOp, pattern string) (*regexp.Regexp, error) {
+	key := regexKey{op: op, pattern: pattern}
+	regexes.Lock()
+	re, ok := regexes.m[key]
+	regexes.Unlock()
+	if ok {
+		return re, nil
+	}
+	expr := pattern
+	if op == MatchRegex {
+		expr = "^(?:" + pattern + ")$"
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		// Report errors in the pattern as given, not in the anchored expression.
+		if _, perr := regexp.Compile(pattern); perr != nil {
+			err = perr
+		}
+		return nil, fmt.Errorf("invalid regular expression %q: %v", pattern, err)
+	}
+	regexes.Lock()
+	if len(regexes.m) >= maxCachedRegexes {
+		regexes.m = make(map[regexKey]*regexp.Regexp)
+	}
+	regexes.m[key] = re
+	regexes.Unlock()
+	return re, nil
+}
+
+// PathVMatch returns the match operator and value of a value path element of the form [<op>value], see MatchOp.
+// Value path elements without an operator, [value] and [:value], use MatchUnanchoredRegex.
+// It returns an error if pe is not a value path element.
+func PathVMatch(pe string) (MatchOp, string, error) {
+	if v, ok := RemoveBrackets(pe); ok {
+		for _, op := range operatorMatchOps {
+			if strings.HasPrefix(v, string(op)) && len(v) > len(op) {
+				return op, unescapeSelectorValue(op, v[len(op):]), nil
+			}
+		}
+	}
+	v, err := PathV(pe)
+	return MatchUnanchoredRegex, v, err
+}
+
+// ValidatePath returns an error if a selector path element of p has an invalid glob pattern or regular expression.
+// Value path elements with an operator or ':', such as [=value] and [:value], are validated as values even if their
+// value contains a separator. Other selectors are validated as key/value path elements if they are one, and as values
+// otherwise.
+func ValidatePath(p Path) error {
+	for _, pe := range p {
+		inner, ok := RemoveBrackets(pe)
+		if !ok || IsNPathElement(pe) || IsWildcardPathElement(pe) {
+			continue
+		}
+		if !isValueSelector(inner) {
+			if kvs, err := PathKVs(pe); err == nil {
+				for _, kv := range kvs {
+					if _, err := MatchValue(kv.Op, kv.Value, ""); err != nil {
+						return fmt.Errorf("path %s: %v", p, err)
+					}
+				}
+				continue
+			}
+		}
+		op, v, err := PathVMatch(pe)
+		if err != nil {
+			return fmt.Errorf("path %s: %v", p, err)
+		}
+		if _, err := MatchValue(op, v, ""); err != nil {
+			return fmt.Errorf("path %s: %v", p, err)
+		}
+	}
+	return nil
+}
+
+// isValueSelector reports whether inner, a selector without its brackets, is a value selector with an operator or
+// ':', which has no key.
+func isValueSelector(inner string) bool {
+	if strings.HasPrefix(inner, KVSeparator) {
+		return true
+	}
+	for _, op := range operatorMatchOps {
+		if strings.HasPrefix(inner, string(op)) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitSelector splits a key/value pair at its first unescaped ':' or match operator.
+func splitSelector(pair string) (key string, op MatchOp, value string, ok bool) {
+	var prev rune
+	for i, c := range pair {
+		if prev == '\\' {
+			// The escaped character does not escape the next one, as in a\\:b.
+			prev = 0
+			continue
+		}
+		switch c {
+		case kvSeparatorRune:
+			value = pair[i+1:]
+			// The value of a key:value pair must not contain unescaped separators.
+			if len(splitEscaped(value, kvSeparatorRune)) > 1 {
+				return "", "", "", false
+			}
+			return pair[:i], MatchExact, value, true
+		case '=':
+			key, op = pair[:i], MatchExact
+			for _, o := range operatorMatchOps {
+				if o != MatchExact && strings.HasSuffix(key, string(o[0])) {
+					key, op = key[:len(key)-1], o
+					break
+				}
+			}
+			return key, op, pair[i+1:], true
+		}
+		prev = c
+	}
+	return "", "", "", false
+}
+
+// unescapeSelectorValue removes the escapes from a selector value. Glob patterns and regular expressions are kept as
+// they are, since escaped characters match themselves literally in both.
+func unescapeSelectorValue(op MatchOp, v string) string {
+	if op == MatchGlob || op == MatchRegex {
+		return v
+	}
+	return selectorUnescaper.Replace(v)
+}
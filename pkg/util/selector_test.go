@@ -0,0 +1,281 @@
+package util
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMatchValue(t *testing.T) {
+	tests := []struct {
+		desc    string
+		op      MatchOp
+		pattern string
+		in      string
+		want    bool
+		wantErr string
+	}{
+		{
+			desc:    "exact",
+			op:      MatchExact,
+			pattern: "vv1",
+			in:      "vv1",
+			want:    true,
+		},
+		{
+			desc:    "exact substring",
+			op:      MatchExact,
+			pattern: "vv1",
+			in:      "xvv10",
+		},
+		{
+			desc:    "prefix",
+			op:      MatchPrefix,
+			pattern: "grpc-",
+			in:      "grpc-xds",
+			want:    true,
+		},
+		{
+			desc:    "prefix no match",
+			op:      MatchPrefix,
+			pattern: "grpc-",
+			in:      "http-grpc-xds",
+		},
+		{
+			desc:    "glob",
+			op:      MatchGlob,
+			pattern: "docker.io/*:1.?",
+			in:      "docker.io/nginx:1.2",
+			want:    true,
+		},
+		{
+			desc:    "glob no match",
+			op:      MatchGlob,
+			pattern: "docker.io/*",
+			in:      "quay.io/nginx",
+		},
+		{
+			desc:    "regex is anchored",
+			op:      MatchRegex,
+			pattern: "vv1|vv2",
+			in:      "xvv10",
+		},
+		{
+			desc:    "regex",
+			op:      MatchRegex,
+			pattern: "vv1|vv2",
+			in:      "vv2",
+			want:    true,
+		},
+		{
+			desc:    "unanchored regex",
+			op:      MatchUnanchoredRegex,
+			pattern: "vv1",
+			in:      "xvv10",
+			want:    true,
+		},
+		{
+			desc:    "invalid regex",
+			op:      MatchRegex,
+			pattern: "v[",
+			wantErr: "invalid regular expression \"v[\": error parsing regexp: missing closing ]: `[`",
+		},
+		{
+			desc:    "invalid unanchored regex",
+			op:      MatchUnanchoredRegex,
+			pattern: "v(",
+			wantErr: "invalid regular expression \"v(\": error parsing regexp: missing closing ): `v(`",
+		},
+		{
+			desc:    "invalid glob",
+			op:      MatchGlob,
+			pattern: "v[",
+			wantErr: "invalid glob pattern \"v[\": syntax error in pattern",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := MatchValue(tt.op, tt.pattern, tt.in)
+			if gotErr := errToString(err); gotErr != tt.wantErr {
+				t.Fatalf("%s: gotErr:%s, wantErr:%s", tt.desc, gotErr, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("%s: got:%v, want:%v", tt.desc, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileRegexCached(t *testing.T) {
+	for _, op := range []MatchOp{MatchRegex, MatchUnanchoredRegex} {
+		re, err := compileRegex(op, "v[0-9]+")
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := compileRegex(op, "v[0-9]+")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if re != again {
+			t.Errorf("%q: compiled again", op)
+		}
+	}
+	if anchored, _ := compileRegex(MatchRegex, "v[0-9]+"); anchored.MatchString("xv1") {
+		t.Errorf("anchored regex matches xv1")
+	}
+}
+
+func TestSplitSelector(t *testing.T) {
+	tests := []struct {
+		in        string
+		wantKey   string
+		wantOp    MatchOp
+		wantValue string
+	}{
+		{in: `a:b`, wantKey: "a", wantOp: MatchExact, wantValue: "b"},
+		{in: `a\:b=c`, wantKey: `a\:b`, wantOp: MatchExact, wantValue: "c"},
+		{in: `a\\:b`, wantKey: `a\\`, wantOp: MatchExact, wantValue: "b"},
+		{in: `a\\=b`, wantKey: `a\\`, wantOp: MatchExact, wantValue: "b"},
+		{in: `a\\~=b`, wantKey: `a\\`, wantOp: MatchRegex, wantValue: "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			key, op, value, ok := splitSelector(tt.in)
+			if !ok || key != tt.wantKey || op != tt.wantOp || value != tt.wantValue {
+				t.Errorf("splitSelector(%q) = %q, %q, %q, %v, want %q, %q, %q", tt.in, key, op, value, ok, tt.wantKey, tt.wantOp, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestCompileRegexCacheBounded(t *testing.T) {
+	for i := 0; i < maxCachedRegexes+10; i++ {
+		if _, err := compileRegex(MatchRegex, fmt.Sprintf("v%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	regexes.Lock()
+	defer regexes.Unlock()
+	if n := len(regexes.m); n > maxCachedRegexes {
+		t.Errorf("cache holds %d regular expressions, want at most %d", n, maxCachedRegexes)
+	}
+}
+
+func TestPathVMatch(t *testing.T) {
+	tests := []struct {
+		desc   string
+		in     string
+		wantOp MatchOp
+		wantV  string
+	}{
+		{
+			desc:   "legacy",
+			in:     "[vv1]",
+			wantOp: MatchUnanchoredRegex,
+			wantV:  "vv1",
+		},
+		{
+			desc:   "legacy value",
+			in:     "[:vv1]",
+			wantOp: MatchUnanchoredRegex,
+			wantV:  "vv1",
+		},
+		{
+			desc:   "legacy key value",
+			in:     "[vv2=foo:bar]",
+			wantOp: MatchUnanchoredRegex,
+			wantV:  "vv2=foo:bar",
+		},
+		{
+			desc:   "exact",
+			in:     `[=a\.b\:c]`,
+			wantOp: MatchExact,
+			wantV:  "a.b:c",
+		},
+		{
+			desc:   "prefix",
+			in:     "[^=--log]",
+			wantOp: MatchPrefix,
+			wantV:  "--log",
+		},
+		{
+			desc:   "glob",
+			in:     "[*=--log*]",
+			wantOp: MatchGlob,
+			wantV:  "--log*",
+		},
+		{
+			desc:   "regex",
+			in:     `[~=--log-level=(debug|info)\.]`,
+			wantOp: MatchRegex,
+			wantV:  `--log-level=(debug|info)\.`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			op, v, err := PathVMatch(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if op != tt.wantOp || v != tt.wantV {
+				t.Errorf("%s: got:%q %q, want:%q %q", tt.desc, op, v, tt.wantOp, tt.wantV)
+			}
+		})
+	}
+}
+
+func TestValidatePath(t *testing.T) {
+	tests := []struct {
+		desc    string
+		in      string
+		wantErr string
+	}{
+		{
+			desc: "valid",
+			in:   `a.[name~=n.*].b.[0].c.[*].d.[v(1|2)]`,
+		},
+		{
+			desc:    "invalid kv regex",
+			in:      `a.[name~=n(].b`,
+			wantErr: "path a.[name~=n(].b: invalid regular expression \"n(\": error parsing regexp: missing closing ): `n(`",
+		},
+		{
+			desc:    "invalid leaf regex",
+			in:      `a.[v(]`,
+			wantErr: "path a.[v(]: invalid regular expression \"v(\": error parsing regexp: missing closing ): `v(`",
+		},
+		{
+			desc:    "invalid glob",
+			in:      `a.[name*=[]]`,
+			wantErr: "path a.[name*=[]]: invalid glob pattern \"[]\": syntax error in pattern",
+		},
+		{
+			desc: "kv value is not a regex",
+			in:   `a.[name:n(]`,
+		},
+		{
+			desc: "leaf value with =",
+			in:   `a.[=--v=2].b.[^=--log=].c.[:k=v]`,
+		},
+		{
+			desc:    "invalid leaf regex with =",
+			in:      `a.[~=k=(]`,
+			wantErr: "path a.[~=k=(]: invalid regular expression \"k=(\": error parsing regexp: missing closing ): `k=(`",
+		},
+		{
+			desc:    "invalid leaf glob with =",
+			in:      `a.[*=k=[]]`,
+			wantErr: "path a.[*=k=[]]: invalid glob pattern \"k=[]\": syntax error in pattern",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			p, err := ParsePath(tt.in)
+			if err == nil {
+				err = ValidatePath(p)
+			}
+			if gotErr := errToString(err); gotErr != tt.wantErr {
+				t.Errorf("%s: gotErr:%s, wantErr:%s", tt.desc, gotErr, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package util
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxSuggestions is the maximum number of strings returned by ClosestStrings.
+const maxSuggestions = 3
+
+// EditDistance returns the optimal string alignment distance between a and b, that is the number of single rune
+// insertions, deletions, substitutions and transpositions of adjacent runes needed to turn a into b.
+func EditDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// d[i][j] is the distance between the first i runes of a and the first j runes of b.
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+// ClosestStrings returns up to three of candidates that are close enough to s to be a likely typo of it, closest
+// first. A candidate is close enough if its edit distance to s is at most a third of the length of s, so nothing is
+// suggested for strings shorter than three runes.
+func ClosestStrings(s string, candidates []string) []string {
+	limit := len([]rune(s)) / 3
+	dist := make(map[string]int)
+	var out []string
+	for _, c := range candidates {
+		if _, ok := dist[c]; ok || c == s {
+			continue
+		}
+		if d := EditDistance(s, c); d <= limit {
+			dist[c] = d
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if dist[out[i]] != dist[out[j]] {
+			return dist[out[i]] < dist[out[j]]
+		}
+		return out[i] < out[j]
+	})
+	if len(out) > maxSuggestions {
+		out = out[:maxSuggestions]
+	}
+	return out
+}
+
+// QuoteList returns items quoted with backticks and joined as a list in prose, with conjunction before the last item,
+// as in "`a`, `b` or `c`".
+func QuoteList(items []string, conjunction string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = "`" + item + "`"
+	}
+	if len(quoted) < 2 {
+		return strings.Join(quoted, "")
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " " + conjunction + " " + quoted[len(quoted)-1]
+}
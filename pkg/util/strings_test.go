@@ -0,0 +1,88 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"template", "template", 0},
+		{"tempalte", "template", 1},
+		{"nodeSelectr", "nodeSelector", 1},
+		{"kitten", "sitting", 3},
+		{"ä", "a", 1},
+	}
+	for _, tt := range tests {
+		if got := EditDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("EditDistance(%q, %q): got:%d, want:%d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestClosestStrings(t *testing.T) {
+	tests := []struct {
+		desc       string
+		s          string
+		candidates []string
+		want       []string
+	}{
+		{
+			desc:       "typo",
+			s:          "tempalte",
+			candidates: []string{"replicas", "selector", "template"},
+			want:       []string{"template"},
+		},
+		{
+			desc:       "closest first",
+			s:          "contaners",
+			candidates: []string{"initContainers", "containers", "containerz"},
+			want:       []string{"containers", "containerz"},
+		},
+		{
+			desc:       "at most three",
+			s:          "abcdefghi",
+			candidates: []string{"abcdefghX", "abcdefgXi", "abcdefXhi", "abcdeXghi"},
+			want:       []string{"abcdeXghi", "abcdefXhi", "abcdefgXi"},
+		},
+		{
+			desc:       "short strings",
+			s:          "ab",
+			candidates: []string{"ac", "b"},
+		},
+		{
+			desc:       "nothing close",
+			s:          "resources",
+			candidates: []string{"image", "name"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := ClosestStrings(tt.s, tt.candidates); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: got:%q, want:%q", tt.desc, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuoteList(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"a"}, "`a`"},
+		{[]string{"a", "b"}, "`a` or `b`"},
+		{[]string{"a", "b", "c"}, "`a`, `b` or `c`"},
+	}
+	for _, tt := range tests {
+		if got := QuoteList(tt.in, "or"); got != tt.want {
+			t.Errorf("QuoteList(%q): got:%s, want:%s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Number is a number that cannot be held by an int64, uint64 or float64 without changing how it is written, such as
+// 1.10, 1e3 or 123456789012345678901234567890. It is marshaled to YAML and JSON exactly as written.
+type Number string
+
+// String implements fmt.Stringer.
+func (n Number) String() string {
+	return string(n)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (n Number) MarshalJSON() ([]byte, error) {
+	return []byte(n), nil
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (n Number) MarshalYAML() (any, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: string(n)}, nil
+}
+
+// Float64 returns n as a float64, which may lose precision.
+func (n Number) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// yaml11Bools are the plain scalars that are booleans in YAML 1.1, which sigs.k8s.io/yaml and thus Kubernetes tooling
+// follow, but strings in YAML 1.2.
+var yaml11Bools = map[string]bool{
+	"y": true, "Y": true, "yes": true, "Yes": true, "YES": true, "on": true, "On": true, "ON": true,
+	"n": false, "N": false, "no": false, "No": false, "NO": false, "off": false, "Off": false, "OFF": false,
+}
+
+// UnmarshalValue unmarshals y, which holds a YAML or JSON value, into a tree of map[string]any, []any and scalars.
+// Unlike unmarshaling through JSON, numbers keep their precision and representation: integers are int64, or uint64
+// if they are too large for int64, and other numbers are float64 if that does not change how they are written, and
+// Number otherwise. Booleans follow YAML 1.1 like the rest of Kubernetes, so plain yes and on are true. An empty y is
+// nil.
+func UnmarshalValue(y string) (any, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(y), &node); err != nil {
+		return nil, err
+	}
+	if node.Kind == 0 {
+		return nil, nil
+	}
+	return nodeValue(&node)
+}
+
+// nodeValue converts a decoded YAML node into a tree of map[string]any, []any and scalars with lossless numbers.
+func nodeValue(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		return nodeValue(node.Content[0])
+	case yaml.AliasNode:
+		return nodeValue(node.Alias)
+	case yaml.MappingNode:
+		m := make(map[string]any, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+			if k.Tag == "!!merge" {
+				// Merge keys are rare in patch values, let the YAML decoder resolve them.
+				var mm map[string]any
+				if err := node.Decode(&mm); err != nil {
+					return nil, err
+				}
+				return normalizeNumbers(mm), nil
+			}
+			var key string
+			if err := k.Decode(&key); err != nil {
+				return nil, fmt.Errorf("line %d: map key: %v", k.Line, err)
+			}
+			val, err := nodeValue(v)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = val
+		}
+		return m, nil
+	case yaml.SequenceNode:
+		l := make([]any, 0, len(node.Content))
+		for _, n := range node.Content {
+			val, err := nodeValue(n)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, val)
+		}
+		return l, nil
+	}
+
+	switch node.ShortTag() {
+	case "!!str":
+		if b, ok := yaml11Bools[node.Value]; ok && node.Style == 0 {
+			return b, nil
+		}
+	case "!!int":
+		if i, err := strconv.ParseInt(node.Value, 0, 64); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(node.Value, 0, 64); err == nil {
+			return u, nil
+		}
+		if json.Valid([]byte(node.Value)) {
+			return Number(node.Value), nil
+		}
+	case "!!float":
+		var f float64
+		if err := node.Decode(&f); err != nil {
+			return nil, err
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) || strconv.FormatFloat(f, 'g', -1, 64) == node.Value {
+			return f, nil
+		}
+		if json.Valid([]byte(node.Value)) {
+			return Number(node.Value), nil
+		}
+		return f, nil
+	}
+	var v any
+	if err := node.Decode(&v); err != nil {
+		return nil, err
+	}
+	return normalizeNumbers(v), nil
+}
+
+// normalizeNumbers converts the int values the YAML decoder produces in v to int64.
+func normalizeNumbers(v any) any {
+	switch vv := v.(type) {
+	case int:
+		return int64(vv)
+	case map[string]any:
+		for k, e := range vv {
+			vv[k] = normalizeNumbers(e)
+		}
+	case []any:
+		for i, e := range vv {
+			vv[i] = normalizeNumbers(e)
+		}
+	}
+	return v
+}
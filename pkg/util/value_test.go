@@ -0,0 +1,81 @@
+package util
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestUnmarshalValue(t *testing.T) {
+	tests := []struct {
+		desc    string
+		in      string
+		want    any
+		wantErr string
+	}{
+		{desc: "empty", in: "", want: nil},
+		{desc: "null", in: "null", want: nil},
+		{desc: "string", in: "abc", want: "abc"},
+		{desc: "quoted number", in: `"3"`, want: "3"},
+		{desc: "bool", in: "true", want: true},
+		{desc: "YAML 1.1 bool", in: "on", want: true},
+		{desc: "quoted YAML 1.1 bool", in: `"no"`, want: "no"},
+		{desc: "int", in: "3", want: int64(3)},
+		{desc: "negative int", in: "-3", want: int64(-3)},
+		{desc: "int beyond float64 precision", in: "9007199254740993", want: int64(9007199254740993)},
+		{desc: "uint64", in: "18446744073709551615", want: uint64(18446744073709551615)},
+		{desc: "int beyond uint64", in: "123456789012345678901234567890", want: Number("123456789012345678901234567890")},
+		{desc: "float", in: "0.5", want: 0.5},
+		{desc: "float with trailing zero", in: "1.10", want: Number("1.10")},
+		{desc: "exponent", in: "1e3", want: Number("1e3")},
+		{desc: "infinity", in: ".inf", want: math.Inf(1)},
+		{
+			desc: "map",
+			in:   "runAsUser: 9007199254740993\nname: app\nratio: 1.0",
+			want: map[string]any{"runAsUser": int64(9007199254740993), "name": "app", "ratio": Number("1.0")},
+		},
+		{
+			desc: "json",
+			in:   `{"size": 10737418240, "items": [1, "b", 2.5]}`,
+			want: map[string]any{"size": int64(10737418240), "items": []any{int64(1), "b", 2.5}},
+		},
+		{
+			desc: "anchors and merge keys",
+			in:   "a: &a\n  x: 1\nb:\n  <<: *a\n  y: 2",
+			want: map[string]any{"a": map[string]any{"x": int64(1)}, "b": map[string]any{"x": int64(1), "y": int64(2)}},
+		},
+		{desc: "invalid", in: "a: [", wantErr: "yaml: line 1: did not find expected node content"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := UnmarshalValue(tt.in)
+			if gotErr := errToString(err); gotErr != tt.wantErr {
+				t.Fatalf("UnmarshalValue(%q): gotErr:%s, wantErr:%s", tt.in, gotErr, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnmarshalValue(%q): got:%#v, want:%#v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNumberMarshal(t *testing.T) {
+	v := map[string]any{"a": Number("1.10"), "b": Number("123456789012345678901234567890")}
+	y, err := yaml.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a: 1.10\nb: 123456789012345678901234567890\n"; string(y) != want {
+		t.Errorf("yaml.Marshal(): got:%q, want:%q", y, want)
+	}
+	j, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a":1.10,"b":123456789012345678901234567890}`; string(j) != want {
+		t.Errorf("json.Marshal(): got:%s, want:%s", j, want)
+	}
+}
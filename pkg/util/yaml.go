@@ -39,6 +39,9 @@ func ToYAML(val any) string {
 }
 
 // UnmarshalWithJSONPB unmarshals y into out using gogo jsonpb (required for many proto defined structs).
+//
+// Deprecated: patch values are decoded with UnmarshalValue, which keeps the precision of numbers. UnmarshalWithJSONPB is
+// kept for existing callers and will be removed in a future release.
 func UnmarshalWithJSONPB(y string, out proto.Message, allowUnknownField bool) error {
 	// Treat nothing as nothing.  If we called jsonpb.Unmarshaler it would return the same.
 	if y == "" {
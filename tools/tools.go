@@ -5,4 +5,5 @@ package tools
 
 import (
 	_ "github.com/golangci/golangci-lint/cmd/golangci-lint"
+	_ "sigs.k8s.io/controller-tools/cmd/controller-gen"
 )